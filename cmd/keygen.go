@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+
+	"github.com/free5gc/udm/pkg/suci"
+)
+
+// keygenCommand generates the home network key pairs of the SUCI profiles and checks
+// existing ones, so that operators need no external scripts
+func keygenCommand() *cli.Command {
+	schemeFlag := &cli.StringFlag{
+		Name:  "scheme",
+		Value: suci.ProfileAScheme,
+		Usage: "Protection scheme `ID`, 1 for Profile A (X25519) or 2 for Profile B (P-256)",
+	}
+	return &cli.Command{
+		Name:  "keygen",
+		Usage: "Generate a home network key pair and print it as a SuciProfile block",
+		Flags: []cli.Flag{
+			schemeFlag,
+			&cli.IntFlag{
+				Name:  "key-id",
+				Value: suci.MinKeyId,
+				Usage: "Home network public key `ID` of the SUCI profile",
+			},
+			&cli.BoolFlag{
+				Name:  "compressed",
+				Usage: "Encode a Profile B public key in compressed form",
+			},
+		},
+		Action: keygenGenerate,
+		Subcommands: []*cli.Command{
+			{
+				Name:  "check",
+				Usage: "Check that a private key matches its public key",
+				Flags: []cli.Flag{
+					schemeFlag,
+					&cli.StringFlag{
+						Name:     "private-key",
+						Required: true,
+						Usage:    "Home network private key `HEX`",
+					},
+					&cli.StringFlag{
+						Name:     "public-key",
+						Required: true,
+						Usage:    "Home network public key `HEX`",
+					},
+				},
+				Action: keygenCheck,
+			},
+		},
+	}
+}
+
+func keygenGenerate(cliCtx *cli.Context) error {
+	profile, err := suci.GenerateProfile(cliCtx.Int("key-id"), cliCtx.String("scheme"),
+		cliCtx.Bool("compressed"))
+	if err != nil {
+		return err
+	}
+
+	block, err := yaml.Marshal(struct {
+		SuciProfiles []suci.SuciProfile `yaml:"SuciProfile"`
+	}{SuciProfiles: []suci.SuciProfile{profile}})
+	if err != nil {
+		return err
+	}
+	_, err = cliCtx.App.Writer.Write(block)
+	return err
+}
+
+func keygenCheck(cliCtx *cli.Context) error {
+	profile := suci.SuciProfile{
+		ProtectionScheme: cliCtx.String("scheme"),
+		PrivateKey:       cliCtx.String("private-key"),
+		PublicKey:        cliCtx.String("public-key"),
+	}
+	if err := suci.ValidateProfile(profile); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(cliCtx.App.Writer, "PublicKey matches PrivateKey")
+	return err
+}
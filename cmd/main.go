@@ -41,6 +41,10 @@ func main() {
 			Usage:   "Output NF log to `FILE`",
 		},
 	}
+	app.Commands = []*cli.Command{
+		suciCommand(),
+		keygenCommand(),
+	}
 
 	if err := app.Run(os.Args); err != nil {
 		logger.MainLog.Errorf("UDM Run error: %v\n", err)
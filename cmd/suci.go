@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/free5gc/udm/pkg/factory"
+	"github.com/free5gc/udm/pkg/suci"
+)
+
+// suciCommand conceals and reveals subscription identifiers with the SUCI profiles of the
+// configuration, e.g. to provision UE simulators
+func suciCommand() *cli.Command {
+	configFlag := &cli.StringFlag{
+		Name:    "config",
+		Aliases: []string{"c"},
+		Usage:   "Load the SUCI profiles from `FILE`",
+	}
+	return &cli.Command{
+		Name:  "suci",
+		Usage: "Conceal a SUPI or reveal the SUPI of a SUCI with the configured SUCI profiles",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "conceal",
+				Usage:     "Conceal a SUPI (imsi-... or nai-...) into a SUCI",
+				ArgsUsage: "SUPI",
+				Flags: []cli.Flag{
+					configFlag,
+					&cli.IntFlag{
+						Name:  "key-id",
+						Usage: "Home network public key `ID` of the SUCI profile, 0 for the null scheme",
+					},
+					&cli.IntFlag{
+						Name:  "mnc-len",
+						Value: 2,
+						Usage: "Number of MNC digits of an IMSI",
+					},
+					&cli.StringFlag{
+						Name:  "routing-indicator",
+						Value: "0",
+						Usage: "Routing indicator of the SUCI, 1 to 4 digits",
+					},
+				},
+				Action: suciConceal,
+			},
+			{
+				Name:      "reveal",
+				Usage:     "Reveal the SUPI of a SUCI",
+				ArgsUsage: "SUCI",
+				Flags:     []cli.Flag{configFlag},
+				Action:    suciReveal,
+			},
+		},
+	}
+}
+
+func loadSuciProfiles(cliCtx *cli.Context) (suci.ProfileTable, error) {
+	cfg, err := factory.ReadConfig(cliCtx.String("config"))
+	if err != nil {
+		return nil, err
+	}
+	return suci.NewProfileTable(cfg.Configuration.SuciProfiles)
+}
+
+func suciConceal(cliCtx *cli.Context) error {
+	if cliCtx.NArg() != 1 {
+		return fmt.Errorf("expected one SUPI argument")
+	}
+
+	var profile *suci.SuciProfile
+	if keyId := cliCtx.Int("key-id"); keyId != 0 {
+		profiles, err := loadSuciProfiles(cliCtx)
+		if err != nil {
+			return err
+		}
+		p, ok := profiles[keyId]
+		if !ok {
+			return fmt.Errorf("no SUCI profile with key ID %d", keyId)
+		}
+		profile = &p
+	}
+
+	suciStr, err := suci.FromSupi(cliCtx.Args().First(), cliCtx.Int("mnc-len"),
+		cliCtx.String("routing-indicator"), profile)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(cliCtx.App.Writer, suciStr)
+	return err
+}
+
+func suciReveal(cliCtx *cli.Context) error {
+	if cliCtx.NArg() != 1 {
+		return fmt.Errorf("expected one SUCI argument")
+	}
+
+	profiles, err := loadSuciProfiles(cliCtx)
+	if err != nil {
+		return err
+	}
+	supi, err := profiles.ToSupi(cliCtx.Args().First())
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(cliCtx.App.Writer, supi)
+	return err
+}
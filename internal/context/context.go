@@ -4,29 +4,42 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"math"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/google/uuid"
 
 	"github.com/free5gc/openapi/models"
 	Nnrf_NFDiscovery "github.com/free5gc/openapi/nrf/NFDisc"
 	"github.com/free5gc/openapi/oauth"
 	"github.com/free5gc/udm/internal/logger"
 	"github.com/free5gc/udm/pkg/factory"
+	"github.com/free5gc/udm/pkg/keyprovider"
+	"github.com/free5gc/udm/pkg/servingnetwork"
+	"github.com/free5gc/udm/pkg/sqn"
 	"github.com/free5gc/udm/pkg/suci"
 	"github.com/free5gc/util/idgenerator"
 )
 
 var udmContext = UDMContext{}
 
+// sqnLockStripes is the number of locks serializing the SQN updates of all SUPIs
+const sqnLockStripes = 256
+
 const (
 	LocationUriAmf3GppAccessRegistration int = iota
 	LocationUriAmfNon3GppAccessRegistration
 	LocationUriSmfRegistration
 	LocationUriSdmSubscription
 	LocationUriSharedDataSubscription
+	LocationUriSmsf3GppAccessRegistration
+	LocationUriSmsfNon3GppAccessRegistration
 )
 
 func Init() {
@@ -44,6 +57,8 @@ var _ NFContext = &UDMContext{}
 type UDMContext struct {
 	NfId                           string
 	GroupId                        string
+	RoutingIndicators              []string          // served, empty serves every SUCI
+	RoutingIndicatorRedirects      map[string]string // routing indicator as key, API root of its UDM as value
 	SBIPort                        int
 	RegisterIPv4                   string // IP register to NRF
 	BindingIPv4                    string
@@ -51,12 +66,22 @@ type UDMContext struct {
 	NfService                      map[models.Nrf_NFMgmt_ServiceName]models.Nrf_NFMgmt_NFService
 	NFDiscoveryClient              *Nnrf_NFDiscovery.APIClient
 	UdmUePool                      sync.Map // map[supi]*UdmUeContext
+	sqnLocks                       [sqnLockStripes]sync.Mutex
+	authFailures                   map[string]*AuthFailureCounters // supi as key
+	authFailuresLock               sync.Mutex
 	NrfUri                         string
 	NrfCertPem                     string
 	GpsiSupiList                   models.Udr_DR_IdentityData
 	SharedSubsDataMap              map[string]models.Udm_SDM_SharedData // sharedDataIds as key
 	SubscriptionOfSharedDataChange sync.Map                             // subscriptionID as key
-	SuciProfiles                   []suci.SuciProfile
+	SuciProfiles                   suci.ProfileTable
+	OperatorOp                     string // hex, operator-wide OP for Milenage
+	OperatorTop                    string // hex, operator-wide TOP for TUAK
+	KeyProvider                    keyprovider.KeyProvider
+	PlaintextKeyIds                []string               // protection parameter IDs of plaintext keys
+	SqnScheme                      *sqn.Scheme            // nil selects sqn.PlainScheme
+	AuthFailurePolicy              *AuthFailurePolicy     // nil never locks a UE out
+	ServingNetworkPolicy           *servingnetwork.Policy // nil accepts any well-formed serving network
 	EeSubscriptionIDGenerator      *idgenerator.IDGenerator
 	OAuth2Required                 bool
 }
@@ -68,6 +93,8 @@ type UdmUeContext struct {
 	Nssai                             *models.Udm_SDM_Nssai
 	Amf3GppAccessRegistration         *models.Udm_UECM_Amf3GppAccessRegistration
 	AmfNon3GppAccessRegistration      *models.Udm_UECM_AmfNon3GppAccessRegistration
+	Smsf3GppAccessRegistration        *models.Udm_UECM_SmsfRegistration
+	SmsfNon3GppAccessRegistration     *models.Udm_UECM_SmsfRegistration
 	AccessAndMobilitySubscriptionData *models.Udm_SDM_AccessAndMobilitySubscriptionData
 	SmfSelSubsData                    *models.Udm_SDM_SmfSelectionSubscriptionData
 	UeCtxtInSmfData                   *models.Udm_SDM_UeContextInSmfData
@@ -77,19 +104,38 @@ type UdmUeContext struct {
 	SubsDataSets                      *models.Udm_SDM_SubscriptionDataSets
 	SubscribeToNotifChange            map[string]*models.Udm_SDM_SdmSubscription
 	SubscribeToNotifSharedDataChange  *models.Udm_SDM_SdmSubscription
-	PduSessionID                      string
+	SmfRegistrations                  map[int32]*models.Udm_UECM_SmfRegistration // PDU session ID as key
 	UdrUri                            string
 	UdmSubsToNotify                   map[string]*models.Udr_DR_SubscriptionDataSubscriptions
 	EeSubscriptions                   map[string]*models.Udm_EvtExpos_EeSubscription // subscriptionID as key
 	amSubsDataLock                    sync.Mutex
 	smfSelSubsDataLock                sync.Mutex
 	SmSubsDataLock                    sync.RWMutex
+	smfRegistrationsLock              sync.RWMutex
 }
 
 func (ue *UdmUeContext) Init() {
 	ue.UdmSubsToNotify = make(map[string]*models.Udr_DR_SubscriptionDataSubscriptions)
 	ue.EeSubscriptions = make(map[string]*models.Udm_EvtExpos_EeSubscription)
 	ue.SubscribeToNotifChange = make(map[string]*models.Udm_SDM_SdmSubscription)
+	ue.SmfRegistrations = make(map[int32]*models.Udm_UECM_SmfRegistration)
+}
+
+// AuthFailurePolicy holds the number of failed authentications after which a UE is
+// temporarily rejected, to stop SIM cloning or brute force probing. A zero threshold
+// disables the corresponding counter.
+type AuthFailurePolicy struct {
+	MaxResyncFailures       int
+	MaxConfirmationFailures int
+	LockoutDuration         time.Duration
+}
+
+// AuthFailureCounters track the failed authentications of a UE since its last
+// successful authentication
+type AuthFailureCounters struct {
+	ResyncFailures       int        `json:"resyncFailures"`
+	ConfirmationFailures int        `json:"confirmationFailures"`
+	LockedUntil          *time.Time `json:"lockedUntil,omitempty"`
 }
 
 type UdmNFContext struct {
@@ -133,11 +179,79 @@ func InitUdmContext(context *UDMContext) {
 	context.NrfCertPem = configuration.NrfCertPem
 	servingNameList := configuration.ServiceNameList
 
-	udmContext.SuciProfiles = configuration.SuciProfiles
+	udmContext.InitAuthentication(configuration)
 
 	udmContext.InitNFService(servingNameList, config.Info.Version)
 }
 
+// InitAuthentication applies the configuration the authentication procedures depend on:
+// SUCI profiles, operator keys, key provider, SQN scheme, authentication failure policy,
+// serving network policy and routing indicators.
+func (context *UDMContext) InitAuthentication(configuration *factory.Configuration) {
+	suciProfiles, err := suci.NewProfileTable(configuration.SuciProfiles)
+	if err != nil {
+		logger.UtilLog.Errorf("SUCI profiles: %+v", err)
+	}
+	context.SuciProfiles = suciProfiles
+	if configuration.OperatorKeys != nil {
+		context.OperatorOp = configuration.OperatorKeys.Op
+		context.OperatorTop = configuration.OperatorKeys.Top
+	}
+	if configuration.KeyProvider != nil {
+		keyStore, err := keyprovider.LoadFileKeyStore(configuration.KeyProvider.Keystore)
+		if err != nil {
+			logger.UtilLog.Fatalf("Key provider: %+v", err)
+		} else {
+			context.KeyProvider = keyStore
+		}
+	}
+	context.PlaintextKeyIds = configuration.PlaintextKeyIds
+	if configuration.SqnScheme != nil {
+		sqnScheme := configuration.SqnScheme.Scheme()
+		context.SqnScheme = &sqnScheme
+	}
+	if policy := configuration.AuthFailurePolicy; policy != nil {
+		context.AuthFailurePolicy = &AuthFailurePolicy{
+			MaxResyncFailures:       policy.MaxResyncFailures,
+			MaxConfirmationFailures: policy.MaxConfirmationFailures,
+			LockoutDuration:         policy.LockoutDuration,
+		}
+	}
+	if configuration.ServingNetworks != nil {
+		policy := configuration.ServingNetworks.Policy()
+		context.ServingNetworkPolicy = &policy
+	}
+	if udmGroup := configuration.UdmGroup; udmGroup != nil {
+		context.GroupId = udmGroup.GroupId
+		context.RoutingIndicators = udmGroup.RoutingIndicators
+		context.RoutingIndicatorRedirects = make(map[string]string)
+		for _, redirect := range udmGroup.Redirects {
+			for _, ri := range redirect.RoutingIndicators {
+				context.RoutingIndicatorRedirects[ri] = redirect.ApiRoot
+			}
+		}
+	}
+}
+
+// RouteSuci tells whether the UDM serves the routing indicator of supiOrSuci and, if not, the
+// API root of the UDM to redirect the request to, empty when the SUCI is to be rejected.
+// SUPIs are always served, as is every SUCI when no routing indicator is configured.
+func (context *UDMContext) RouteSuci(supiOrSuci string) (served bool, redirectApiRoot string) {
+	if len(context.RoutingIndicators) == 0 {
+		return true, ""
+	}
+	routingIndicator, ok := suci.RoutingIndicator(supiOrSuci)
+	if !ok {
+		return true, ""
+	}
+	for _, ri := range context.RoutingIndicators {
+		if ri == routingIndicator {
+			return true, ""
+		}
+	}
+	return false, context.RoutingIndicatorRedirects[routingIndicator]
+}
+
 func (context *UDMContext) ManageSmData(
 	smDatafromUDR []models.Udm_SDM_SessionManagementSubscriptionData,
 	snssaiFromReq string, dnnFromReq string,
@@ -302,6 +416,17 @@ func (context *UDMContext) NewUdmUe(supi string) *UdmUeContext {
 	return ue
 }
 
+// LockSqn serializes the procedures reading and updating the SQN of supi, so that
+// concurrent requests never issue vectors with the same SQN. The returned function
+// releases the lock. SUPIs share a fixed number of locks, picked by a hash of the SUPI.
+func (context *UDMContext) LockSqn(supi string) func() {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(supi))
+	mutex := &context.sqnLocks[hash.Sum32()%sqnLockStripes]
+	mutex.Lock()
+	return mutex.Unlock
+}
+
 func (context *UDMContext) UdmUeFindBySupi(supi string) (*UdmUeContext, bool) {
 	if value, ok := context.UdmUePool.Load(supi); ok {
 		return value.(*UdmUeContext), ok
@@ -359,14 +484,26 @@ func (context *UDMContext) UdmAmfNon3gppRegContextExists(supi string) bool {
 	}
 }
 
-func (context *UDMContext) UdmSmfRegContextNotExists(supi string) bool {
+func (context *UDMContext) UdmSmsf3gppRegContextExists(supi string) bool {
 	if ue, ok := context.UdmUeFindBySupi(supi); ok {
-		return ue.PduSessionID == ""
+		return ue.Smsf3GppAccessRegistration != nil
 	} else {
-		return true
+		return false
 	}
 }
 
+func (context *UDMContext) UdmSmsfNon3gppRegContextExists(supi string) bool {
+	if ue, ok := context.UdmUeFindBySupi(supi); ok {
+		return ue.SmsfNon3GppAccessRegistration != nil
+	} else {
+		return false
+	}
+}
+
+func (context *UDMContext) UdmSmfRegContextNotExists(supi string, pduSessionID int32) bool {
+	return context.GetSmfRegContext(supi, pduSessionID) == nil
+}
+
 func (context *UDMContext) CreateAmf3gppRegContext(supi string, body models.Udm_UECM_Amf3GppAccessRegistration) {
 	ue, ok := context.UdmUeFindBySupi(supi)
 	if !ok {
@@ -383,14 +520,68 @@ func (context *UDMContext) CreateAmfNon3gppRegContext(supi string, body models.U
 	ue.AmfNon3GppAccessRegistration = &body
 }
 
-func (context *UDMContext) CreateSmfRegContext(supi string, pduSessionID string) {
+func (context *UDMContext) CreateSmfRegContext(supi string, pduSessionID int32, body models.Udm_UECM_SmfRegistration) {
+	ue, ok := context.UdmUeFindBySupi(supi)
+	if !ok {
+		ue = context.NewUdmUe(supi)
+	}
+	// the PDU session ID of the resource URI prevails over the one of the body
+	body.PduSessionId = pduSessionID
+	ue.smfRegistrationsLock.Lock()
+	defer ue.smfRegistrationsLock.Unlock()
+	ue.SmfRegistrations[pduSessionID] = &body
+}
+
+// UpdateSmfRegContext applies the modification to the SMF registration of the PDU session,
+// it returns false if the PDU session has no SMF registration
+func (context *UDMContext) UpdateSmfRegContext(supi string, pduSessionID int32,
+	modification models.Udm_UECM_SmfRegistrationModification,
+) bool {
+	ue, ok := context.UdmUeFindBySupi(supi)
+	if !ok {
+		return false
+	}
+	ue.smfRegistrationsLock.Lock()
+	defer ue.smfRegistrationsLock.Unlock()
+	current, ok := ue.SmfRegistrations[pduSessionID]
+	if !ok {
+		return false
+	}
+	// registrations are replaced rather than modified in place, callers may hold the old one
+	updated := *current
+	updated.SmfInstanceId = modification.SmfInstanceId
+	if modification.SmfSetId != "" {
+		updated.SmfSetId = modification.SmfSetId
+	}
+	if modification.PgwFqdn != "" {
+		updated.PgwFqdn = string(modification.PgwFqdn)
+	}
+	ue.SmfRegistrations[pduSessionID] = &updated
+	return true
+}
+
+func (context *UDMContext) DeleteSmfRegContext(supi string, pduSessionID int32) {
+	if ue, ok := context.UdmUeFindBySupi(supi); ok {
+		ue.smfRegistrationsLock.Lock()
+		defer ue.smfRegistrationsLock.Unlock()
+		delete(ue.SmfRegistrations, pduSessionID)
+	}
+}
+
+func (context *UDMContext) CreateSmsf3gppRegContext(supi string, body models.Udm_UECM_SmsfRegistration) {
 	ue, ok := context.UdmUeFindBySupi(supi)
 	if !ok {
 		ue = context.NewUdmUe(supi)
 	}
-	if ue.PduSessionID == "" {
-		ue.PduSessionID = pduSessionID
+	ue.Smsf3GppAccessRegistration = &body
+}
+
+func (context *UDMContext) CreateSmsfNon3gppRegContext(supi string, body models.Udm_UECM_SmsfRegistration) {
+	ue, ok := context.UdmUeFindBySupi(supi)
+	if !ok {
+		ue = context.NewUdmUe(supi)
 	}
+	ue.SmsfNon3GppAccessRegistration = &body
 }
 
 func (context *UDMContext) GetAmf3gppRegContext(supi string) *models.Udm_UECM_Amf3GppAccessRegistration {
@@ -409,20 +600,242 @@ func (context *UDMContext) GetAmfNon3gppRegContext(supi string) *models.Udm_UECM
 	}
 }
 
+func (context *UDMContext) GetSmfRegContext(supi string, pduSessionID int32) *models.Udm_UECM_SmfRegistration {
+	if ue, ok := context.UdmUeFindBySupi(supi); ok {
+		ue.smfRegistrationsLock.RLock()
+		defer ue.smfRegistrationsLock.RUnlock()
+		return ue.SmfRegistrations[pduSessionID]
+	} else {
+		return nil
+	}
+}
+
+func (context *UDMContext) GetSmsf3gppRegContext(supi string) *models.Udm_UECM_SmsfRegistration {
+	if ue, ok := context.UdmUeFindBySupi(supi); ok {
+		return ue.Smsf3GppAccessRegistration
+	} else {
+		return nil
+	}
+}
+
+func (context *UDMContext) GetSmsfNon3gppRegContext(supi string) *models.Udm_UECM_SmsfRegistration {
+	if ue, ok := context.UdmUeFindBySupi(supi); ok {
+		return ue.SmsfNon3GppAccessRegistration
+	} else {
+		return nil
+	}
+}
+
+// maxAuthFailureRecords bounds the SUPIs whose failed authentications are counted, so that
+// requests for arbitrary SUPIs cannot grow the records without limit
+const maxAuthFailureRecords = 10000
+
+// authFailureRecord returns the failure counters of supi and creates them if there is room.
+// When the records are full, the ones without a running lockout are dropped; nil is returned
+// if every record holds a lockout. The caller holds authFailuresLock.
+func (context *UDMContext) authFailureRecord(supi string, now time.Time) *AuthFailureCounters {
+	if record, ok := context.authFailures[supi]; ok {
+		return record
+	}
+	if context.authFailures == nil {
+		context.authFailures = make(map[string]*AuthFailureCounters)
+	}
+	if len(context.authFailures) >= maxAuthFailureRecords {
+		for recordSupi, record := range context.authFailures {
+			if !record.lockedOut(now) {
+				delete(context.authFailures, recordSupi)
+			}
+		}
+		if len(context.authFailures) >= maxAuthFailureRecords {
+			logger.CtxLog.Warnf("Authentication failures of supi[%s] are not counted, %d UEs are locked out",
+				supi, len(context.authFailures))
+			return nil
+		}
+	}
+	record := new(AuthFailureCounters)
+	context.authFailures[supi] = record
+	return record
+}
+
+// RecordResyncFailure counts a re-synchronization of supi whose MAC-S is wrong and reports
+// whether the UE is now locked out
+func (context *UDMContext) RecordResyncFailure(supi string) bool {
+	context.authFailuresLock.Lock()
+	defer context.authFailuresLock.Unlock()
+
+	record := context.authFailureRecord(supi, time.Now())
+	if record == nil {
+		return false
+	}
+	record.ResyncFailures++
+	policy := context.AuthFailurePolicy
+	if policy == nil || policy.MaxResyncFailures == 0 || record.ResyncFailures < policy.MaxResyncFailures {
+		return false
+	}
+	record.lockOut(policy)
+	return true
+}
+
+// RecordConfirmationFailure counts an authentication of supi the AUSF reports as failed and
+// reports whether the UE is now locked out
+func (context *UDMContext) RecordConfirmationFailure(supi string) bool {
+	context.authFailuresLock.Lock()
+	defer context.authFailuresLock.Unlock()
+
+	record := context.authFailureRecord(supi, time.Now())
+	if record == nil {
+		return false
+	}
+	record.ConfirmationFailures++
+	policy := context.AuthFailurePolicy
+	if policy == nil || policy.MaxConfirmationFailures == 0 ||
+		record.ConfirmationFailures < policy.MaxConfirmationFailures {
+		return false
+	}
+	record.lockOut(policy)
+	return true
+}
+
+// ResetAuthFailures clears the counters of supi after a successful authentication,
+// a running lockout is kept
+func (context *UDMContext) ResetAuthFailures(supi string) {
+	context.authFailuresLock.Lock()
+	defer context.authFailuresLock.Unlock()
+
+	record, ok := context.authFailures[supi]
+	if !ok {
+		return
+	}
+	if !record.lockedOut(time.Now()) {
+		delete(context.authFailures, supi)
+		return
+	}
+	record.ResyncFailures = 0
+	record.ConfirmationFailures = 0
+}
+
+// ClearAuthFailures drops the failure counters of supi, lifting a running lockout
+func (context *UDMContext) ClearAuthFailures(supi string) {
+	context.authFailuresLock.Lock()
+	defer context.authFailuresLock.Unlock()
+
+	delete(context.authFailures, supi)
+}
+
+// AuthLockedOut tells whether the authentication of supi is rejected at now
+func (context *UDMContext) AuthLockedOut(supi string, now time.Time) bool {
+	context.authFailuresLock.Lock()
+	defer context.authFailuresLock.Unlock()
+
+	record, ok := context.authFailures[supi]
+	return ok && record.lockedOut(now)
+}
+
+// GetAuthFailures returns a copy of the failure counters of supi, zero if it has none
+func (context *UDMContext) GetAuthFailures(supi string) AuthFailureCounters {
+	context.authFailuresLock.Lock()
+	defer context.authFailuresLock.Unlock()
+
+	if record, ok := context.authFailures[supi]; ok {
+		return *record
+	}
+	return AuthFailureCounters{}
+}
+
+// GetAllAuthFailures returns a copy of the failure counters of every SUPI that has some
+func (context *UDMContext) GetAllAuthFailures() map[string]AuthFailureCounters {
+	context.authFailuresLock.Lock()
+	defer context.authFailuresLock.Unlock()
+
+	records := make(map[string]AuthFailureCounters, len(context.authFailures))
+	for supi, record := range context.authFailures {
+		records[supi] = *record
+	}
+	return records
+}
+
+// lockOut rejects the UE for the lockout duration and restarts the counting
+func (counters *AuthFailureCounters) lockOut(policy *AuthFailurePolicy) {
+	lockedUntil := time.Now().Add(policy.LockoutDuration)
+	*counters = AuthFailureCounters{LockedUntil: &lockedUntil}
+}
+
+func (counters *AuthFailureCounters) lockedOut(now time.Time) bool {
+	return counters.LockedUntil != nil && now.Before(*counters.LockedUntil)
+}
+
 func (ue *UdmUeContext) GetLocationURI(types int) string {
 	switch types {
 	case LocationUriAmf3GppAccessRegistration:
 		return GetSelf().GetIPv4Uri() + factory.UdmUecmResUriPrefix + "/" + ue.Supi + "/registrations/amf-3gpp-access"
 	case LocationUriAmfNon3GppAccessRegistration:
 		return GetSelf().GetIPv4Uri() + factory.UdmUecmResUriPrefix + "/" + ue.Supi + "/registrations/amf-non-3gpp-access"
-	case LocationUriSmfRegistration:
+	case LocationUriSmsf3GppAccessRegistration:
+		return GetSelf().GetIPv4Uri() + factory.UdmUecmResUriPrefix + "/" + ue.Supi + "/registrations/smsf-3gpp-access"
+	case LocationUriSmsfNon3GppAccessRegistration:
+		return GetSelf().GetIPv4Uri() + factory.UdmUecmResUriPrefix + "/" + ue.Supi + "/registrations/smsf-non-3gpp-access"
+	}
+	return ""
+}
+
+// AuthEventId identifies the authentication event of supi stored in the UDR. It is derived
+// from the event itself, so that it can be checked against the UDR after a restart of the UDM.
+func AuthEventId(supi string, authEvent *models.Udm_UEAU_AuthEvent) string {
+	name := supi + "/" + authEvent.NfInstanceId + "/" + authEvent.ServingNetworkName
+	if authEvent.TimeStamp != nil {
+		// the UDR may not keep the sub-second part of the time stamp
+		name += "/" + authEvent.TimeStamp.UTC().Truncate(time.Second).Format(time.RFC3339)
+	}
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(name)).String()
+}
+
+// GetAuthEventLocationURI returns the URI of the authentication event authEventId of supi
+func GetAuthEventLocationURI(supi, authEventId string) string {
+	return GetSelf().GetIPv4Uri() + factory.UdmUeauResUriPrefix + "/" + supi + "/auth-events/" + authEventId
+}
 
-		return GetSelf().GetIPv4Uri() +
-			factory.UdmUecmResUriPrefix + "/" + ue.Supi + "/registrations/smf-registrations/" + ue.PduSessionID
+// GetPduSessionLocationURI returns the URI of a resource of the UE per PDU session
+func (ue *UdmUeContext) GetPduSessionLocationURI(types int, pduSessionID int32) string {
+	switch types {
+	case LocationUriSmfRegistration:
+		return GetSelf().GetIPv4Uri() + factory.UdmUecmResUriPrefix + "/" + ue.Supi +
+			"/registrations/smf-registrations/" + strconv.Itoa(int(pduSessionID))
 	}
 	return ""
 }
 
+// GetSmfRegistrations returns the SMF registrations of the UE matching singleNssai and dnn,
+// ordered by PDU session ID. A nil singleNssai or an empty dnn matches any registration.
+func (ue *UdmUeContext) GetSmfRegistrations(singleNssai *models.Snssai, dnn string) []models.Udm_UECM_SmfRegistration {
+	ue.smfRegistrationsLock.RLock()
+	defer ue.smfRegistrationsLock.RUnlock()
+
+	registrations := make([]models.Udm_UECM_SmfRegistration, 0, len(ue.SmfRegistrations))
+	for _, registration := range ue.SmfRegistrations {
+		if SmfRegistrationMatches(registration, singleNssai, dnn) {
+			registrations = append(registrations, *registration)
+		}
+	}
+	sort.Slice(registrations, func(i, j int) bool {
+		return registrations[i].PduSessionId < registrations[j].PduSessionId
+	})
+	return registrations
+}
+
+// SmfRegistrationMatches tells whether registration is for singleNssai and dnn, a nil
+// singleNssai or an empty dnn matches any registration. DNNs compare case-insensitively.
+func SmfRegistrationMatches(registration *models.Udm_UECM_SmfRegistration, singleNssai *models.Snssai,
+	dnn string,
+) bool {
+	if singleNssai != nil {
+		if registration.SingleNssai == nil || registration.SingleNssai.Sst != singleNssai.Sst ||
+			!strings.EqualFold(registration.SingleNssai.Sd, singleNssai.Sd) {
+			return false
+		}
+	}
+	return dnn == "" || strings.EqualFold(registration.Dnn, dnn)
+}
+
 func (ue *UdmUeContext) GetLocationURI2(types int, supi string) string {
 	switch types {
 	case LocationUriSharedDataSubscription:
@@ -22,6 +22,7 @@ var (
 	SdmLog      *logrus.Entry
 	PpLog       *logrus.Entry
 	EeLog       *logrus.Entry
+	UeidLog     *logrus.Entry
 	UtilLog     *logrus.Entry
 	SuciLog     *logrus.Entry
 	CallbackLog *logrus.Entry
@@ -50,6 +51,7 @@ func init() {
 	SdmLog = NfLog.WithField(logger_util.FieldCategory, "SDM")
 	PpLog = NfLog.WithField(logger_util.FieldCategory, "PP")
 	EeLog = NfLog.WithField(logger_util.FieldCategory, "EE")
+	UeidLog = NfLog.WithField(logger_util.FieldCategory, "UEID")
 	UtilLog = NfLog.WithField(logger_util.FieldCategory, "Util")
 	SuciLog = NfLog.WithField(logger_util.FieldCategory, "Suci")
 	CallbackLog = NfLog.WithField(logger_util.FieldCategory, "Callback")
@@ -0,0 +1,86 @@
+package sbi
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/udm/internal/logger"
+	"github.com/free5gc/util/metrics/sbi"
+	"github.com/free5gc/util/validator"
+)
+
+func (s *Server) getAdminRoutes() []Route {
+	return []Route{
+		{
+			"GetAuthFailures",
+			http.MethodGet,
+			"/auth-failures",
+			s.HandleGetAuthFailures,
+		},
+
+		{
+			"GetAuthFailure",
+			http.MethodGet,
+			"/auth-failures/:supi",
+			s.HandleGetAuthFailure,
+		},
+
+		{
+			"DeleteAuthFailure",
+			http.MethodDelete,
+			"/auth-failures/:supi",
+			s.HandleDeleteAuthFailure,
+		},
+	}
+}
+
+// GetAuthFailures - the authentication failure counters of every UE with failures or a lockout
+func (s *Server) HandleGetAuthFailures(c *gin.Context) {
+	logger.UeauLog.Infoln("Handle GetAuthFailures")
+
+	s.Processor().GetAuthFailuresProcedure(c)
+}
+
+// GetAuthFailure - the authentication failure counters of a UE
+func (s *Server) HandleGetAuthFailure(c *gin.Context) {
+	supi := c.Params.ByName("supi")
+	if !validator.IsValidSupi(supi) {
+		problemDetail := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: "Supi is invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UeauLog.Warnln("Supi is invalid")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	logger.UeauLog.Infoln("Handle GetAuthFailure")
+
+	s.Processor().GetAuthFailureProcedure(c, supi)
+}
+
+// DeleteAuthFailure - clear the authentication failure counters and the lockout of a UE
+func (s *Server) HandleDeleteAuthFailure(c *gin.Context) {
+	supi := c.Params.ByName("supi")
+	if !validator.IsValidSupi(supi) {
+		problemDetail := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: "Supi is invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UeauLog.Warnln("Supi is invalid")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	logger.UeauLog.Infoln("Handle DeleteAuthFailure")
+
+	s.Processor().DeleteAuthFailureProcedure(c, supi)
+}
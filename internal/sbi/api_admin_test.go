@@ -0,0 +1,46 @@
+package sbi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	udm_context "github.com/free5gc/udm/internal/context"
+	"github.com/free5gc/udm/internal/sbi/consumer"
+	"github.com/free5gc/udm/internal/sbi/processor"
+	"github.com/free5gc/udm/pkg/factory"
+)
+
+func TestAdminRoutesOnlyOnAdminRouter(t *testing.T) {
+	udmCtx := &udm_context.UDMContext{}
+	testApp := &traceDataTestApp{udmCtx: udmCtx}
+	var err error
+	testApp.consumer, err = consumer.NewConsumer(testApp)
+	require.NoError(t, err)
+	testApp.processor, err = processor.NewProcessor(testApp)
+	require.NoError(t, err)
+	server := &Server{ServerUdm: testApp}
+
+	const supi = "imsi-208930000000001"
+	udmCtx.RecordResyncFailure(supi)
+	target := factory.UdmAdminResUriPrefix + "/auth-failures/" + supi
+
+	// the SBI does not serve the admin API
+	recorder := httptest.NewRecorder()
+	newRouter(server).ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, target, nil))
+	require.Equal(t, http.StatusNotFound, recorder.Code)
+	require.Equal(t, 1, udmCtx.GetAuthFailures(supi).ResyncFailures)
+
+	adminRouter := newAdminRouter(server)
+	recorder = httptest.NewRecorder()
+	adminRouter.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, target, nil))
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+	require.Equal(t, 0, udmCtx.GetAuthFailures(supi).ResyncFailures)
+
+	recorder = httptest.NewRecorder()
+	adminRouter.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete,
+		factory.UdmAdminResUriPrefix+"/auth-failures/not-a-supi", nil))
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
@@ -2,6 +2,7 @@ package sbi
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -9,6 +10,8 @@ import (
 	"github.com/free5gc/openapi"
 	"github.com/free5gc/openapi/models"
 	"github.com/free5gc/udm/internal/logger"
+	"github.com/free5gc/udm/internal/sbi/processor"
+	"github.com/free5gc/udm/pkg/suci"
 	"github.com/free5gc/util/metrics/sbi"
 	"github.com/free5gc/util/validator"
 )
@@ -24,6 +27,12 @@ func (s *Server) getUEAuthenticationRoutes() []Route {
 	}
 }
 
+// isValidSupiOrSuci accepts a SUPI or a SUCI; SUCIs are checked by the suci parser, which
+// knows NAI SUCIs (suci-1) and key IDs above 99 that the validator package rejects
+func isValidSupiOrSuci(supiOrSuci string) bool {
+	return validator.IsValidSupi(supiOrSuci) || suci.IsValidSuci(supiOrSuci)
+}
+
 // ConfirmAuth - Create a new confirmation event
 func (s *Server) HandleConfirmAuth(c *gin.Context) {
 	var authEvent models.Udm_UEAU_AuthEvent
@@ -111,7 +120,7 @@ func (s *Server) HandleGenerateAuthData(c *gin.Context) {
 	// TS 29.503 6.3.3.2.2
 	// Validate SUPI or SUCI format
 	supiOrSuci := c.Param("supiOrSuci")
-	if !validator.IsValidSupi(supiOrSuci) && !validator.IsValidSuci(supiOrSuci) {
+	if !isValidSupiOrSuci(supiOrSuci) {
 		problemDetail := models.ProblemDetails{
 			Title:  "Malformed request syntax",
 			Status: http.StatusBadRequest,
@@ -180,24 +189,396 @@ func (s *Server) HandleGenerateAuthData(c *gin.Context) {
 	s.Processor().GenerateAuthDataProcedure(c, authInfoReq, supiOrSuci)
 }
 
+// DeleteAuth - Deletes the authentication result in the UDM
 func (s *Server) HandleDeleteAuth(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
+	var authEvent models.Udm_UEAU_AuthEvent
+	// TS 29.503 6.3.3.2.4
+	// Validate SUPI format
+	supi := c.Params.ByName("supi")
+	if !validator.IsValidSupi(supi) {
+		problemDetail := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: "Supi is invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UeauLog.Warnln("Supi is invalid")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	authEventId := c.Params.ByName("thirdLayer")
+	if authEventId == "" {
+		problemDetail := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: "AuthEventId is missing",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UeauLog.Warnln("AuthEventId is missing")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	requestBody, err := c.GetRawData()
+	if err != nil {
+		problemDetail := models.ProblemDetails{
+			Title:  "System failure",
+			Status: http.StatusInternalServerError,
+			Detail: err.Error(),
+			Cause:  "SYSTEM_FAILURE",
+		}
+		logger.UeauLog.Errorf("Get Request Body error: %+v", err)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetail.Cause)
+		c.JSON(http.StatusInternalServerError, problemDetail)
+		return
+	}
+
+	err = openapi.Deserialize(&authEvent, requestBody, "application/json")
+	if err != nil {
+		problemDetail := "[Request Body] " + err.Error()
+		rsp := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: problemDetail,
+		}
+		logger.UeauLog.Errorln(problemDetail)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(rsp.Status)))
+		c.JSON(int(rsp.Status), rsp)
+		return
+	}
+
+	// TS 29.503 6.3.6.2.7 requirements check, the removal indication shall be set to true
+	missingIEList := make([]string, 0)
+	if authEvent.NfInstanceId == "" {
+		missingIEList = append(missingIEList, "nfInstanceId")
+	}
+	if authEvent.TimeStamp == nil {
+		missingIEList = append(missingIEList, "timestamp")
+	}
+	if authEvent.AuthType == "" {
+		missingIEList = append(missingIEList, "authtype")
+	}
+	if authEvent.ServingNetworkName == "" {
+		missingIEList = append(missingIEList, "servingNetworkName")
+	}
+	if !authEvent.AuthRemovalInd {
+		missingIEList = append(missingIEList, "authRemovalInd")
+	}
+
+	if len(missingIEList) > 0 {
+		missingIEs := strings.Join(missingIEList, ", ")
+		problemDetail := models.ProblemDetails{
+			Title:  "Missing or invalid parameter",
+			Status: http.StatusBadRequest,
+			Detail: "Mandatory IE [" + missingIEs + "] is missing or invalid",
+			Cause:  "MANDATORY_IE_MISSING",
+		}
+		logger.UeauLog.Warnln("Mandatory IE [" + missingIEs + "] is missing or invalid")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	logger.UeauLog.Infoln("Handle DeleteAuthRequest")
+
+	s.Processor().DeleteAuthProcedure(c, authEvent, supi, authEventId)
 }
 
+// GenerateAv - Generate authentication vectors for the HSS (EPS, IMS, GBA and UMTS AKA)
 func (s *Server) HandleGenerateAv(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
+	if c.Request.Method != http.MethodPost {
+		c.String(http.StatusNotFound, "404 page not found")
+		return
+	}
+
+	var hssAuthInfoReq models.Udm_UEAU_HssAuthenticationInfoRequest
+	// Validate SUPI format
+	supi := c.Params.ByName("supi")
+	if !validator.IsValidSupi(supi) {
+		problemDetail := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: "Supi is invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UeauLog.Warnln("Supi is invalid")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	requestBody, err := c.GetRawData()
+	if err != nil {
+		problemDetail := models.ProblemDetails{
+			Title:  "System failure",
+			Status: http.StatusInternalServerError,
+			Detail: err.Error(),
+			Cause:  "SYSTEM_FAILURE",
+		}
+		logger.UeauLog.Errorf("Get Request Body error: %+v", err)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetail.Cause)
+		c.JSON(http.StatusInternalServerError, problemDetail)
+		return
+	}
+
+	err = openapi.Deserialize(&hssAuthInfoReq, requestBody, "application/json")
+	if err != nil {
+		problemDetail := "[Request Body] " + err.Error()
+		rsp := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: problemDetail,
+		}
+		logger.UeauLog.Errorln(problemDetail)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(rsp.Status)))
+		c.JSON(int(rsp.Status), rsp)
+		return
+	}
+
+	// HssAuthenticationInfoRequest requirements check, the hssAuthType of the body
+	// has to match the one of the resource
+	hssAuthType := models.Udm_UEAU_HssAuthType(c.Param("hssAuthType"))
+	if hssAuthInfoReq.HssAuthType == "" {
+		hssAuthInfoReq.HssAuthType = hssAuthType
+	}
+	if hssAuthInfoReq.NumOfRequestedVectors == 0 {
+		hssAuthInfoReq.NumOfRequestedVectors = 1
+	}
+
+	invalidIEList := make([]string, 0)
+	if hssAuthInfoReq.HssAuthType != hssAuthType {
+		invalidIEList = append(invalidIEList, "hssAuthType")
+	}
+	if hssAuthInfoReq.NumOfRequestedVectors < 1 ||
+		hssAuthInfoReq.NumOfRequestedVectors > processor.MaxNumOfRequestedVectors {
+		invalidIEList = append(invalidIEList, "numOfRequestedVectors")
+	}
+
+	if len(invalidIEList) > 0 {
+		invalidIEs := strings.Join(invalidIEList, ", ")
+		problemDetail := models.ProblemDetails{
+			Title:  "Missing or invalid parameter",
+			Status: http.StatusBadRequest,
+			Detail: "Mandatory IE [" + invalidIEs + "] is missing or invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UeauLog.Warnln("Mandatory IE [" + invalidIEs + "] is missing or invalid")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	switch hssAuthType {
+	case models.Udm_UEAU_HssAuthType_EPS_AKA:
+		// KASME is bound to the serving network
+		if hssAuthInfoReq.ServingNetworkId == nil {
+			problemDetail := models.ProblemDetails{
+				Title:  "Missing or invalid parameter",
+				Status: http.StatusBadRequest,
+				Detail: "Mandatory IE [servingNetworkId] is missing or invalid",
+				Cause:  "MANDATORY_IE_MISSING",
+			}
+			logger.UeauLog.Warnln("Mandatory IE [servingNetworkId] is missing or invalid")
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+			c.JSON(int(problemDetail.Status), problemDetail)
+			return
+		}
+	case models.Udm_UEAU_HssAuthType_IMS_AKA,
+		models.Udm_UEAU_HssAuthType_UMTS_AKA,
+		models.Udm_UEAU_HssAuthType_EAP_AKA,
+		models.Udm_UEAU_HssAuthType_GBA_AKA:
+	default:
+		problemDetail := models.ProblemDetails{
+			Title:  "Unsupported hssAuthType",
+			Status: http.StatusNotImplemented,
+			Detail: "hssAuthType [" + string(hssAuthType) + "] is not supported",
+			Cause:  "UNSUPPORTED_HSS_AUTH_TYPE",
+		}
+		logger.UeauLog.Warnln("hssAuthType [" + string(hssAuthType) + "] is not supported")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	logger.UeauLog.Infoln("Handle GenerateAvRequest")
+
+	s.Processor().GenerateAvProcedure(c, hssAuthInfoReq, supi)
 }
 
+// GenerateGbaAv - Generate GBA Authentication Vectors
 func (s *Server) HandleGenerateGbaAv(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
+	var gbaAuthInfoReq models.Udm_UEAU_GbaAuthenticationInfoRequest
+	// Validate SUPI format
+	supi := c.Params.ByName("supi")
+	if !validator.IsValidSupi(supi) {
+		problemDetail := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: "Supi is invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UeauLog.Warnln("Supi is invalid")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	requestBody, err := c.GetRawData()
+	if err != nil {
+		problemDetail := models.ProblemDetails{
+			Title:  "System failure",
+			Status: http.StatusInternalServerError,
+			Detail: err.Error(),
+			Cause:  "SYSTEM_FAILURE",
+		}
+		logger.UeauLog.Errorf("Get Request Body error: %+v", err)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetail.Cause)
+		c.JSON(http.StatusInternalServerError, problemDetail)
+		return
+	}
+
+	err = openapi.Deserialize(&gbaAuthInfoReq, requestBody, "application/json")
+	if err != nil {
+		problemDetail := "[Request Body] " + err.Error()
+		rsp := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: problemDetail,
+		}
+		logger.UeauLog.Errorln(problemDetail)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(rsp.Status)))
+		c.JSON(int(rsp.Status), rsp)
+		return
+	}
+
+	// authType is mandatory and DIGEST_AKAV1_MD5 is the only defined value
+	if gbaAuthInfoReq.AuthType != models.Udm_UEAU_GbaAuthType_DIGEST_AKAV1_MD5 {
+		problemDetail := models.ProblemDetails{
+			Title:  "Missing or invalid parameter",
+			Status: http.StatusBadRequest,
+			Detail: "Mandatory IE [authType] is missing or invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UeauLog.Warnln("Mandatory IE [authType] is missing or invalid")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	logger.UeauLog.Infoln("Handle GenerateGbaAvRequest")
+
+	s.Processor().GenerateGbaAvProcedure(c, gbaAuthInfoReq, supi)
 }
 
+// GenerateProseAV - Generate authentication data for ProSe
 func (s *Server) HandleGenerateProseAV(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
+	var proseAuthInfoReq models.Udm_UEAU_ProSeAuthenticationInfoRequest
+	// Validate SUPI or SUCI format
+	supiOrSuci := c.Param("supiOrSuci")
+	if !isValidSupiOrSuci(supiOrSuci) {
+		problemDetail := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: "Supi or Suci is invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UeauLog.Warnln("Supi or Suci is invalid")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	requestBody, err := c.GetRawData()
+	if err != nil {
+		problemDetail := models.ProblemDetails{
+			Title:  "System failure",
+			Status: http.StatusInternalServerError,
+			Detail: err.Error(),
+			Cause:  "SYSTEM_FAILURE",
+		}
+		logger.UeauLog.Errorf("Get Request Body error: %+v", err)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetail.Cause)
+		c.JSON(http.StatusInternalServerError, problemDetail)
+		return
+	}
+
+	err = openapi.Deserialize(&proseAuthInfoReq, requestBody, "application/json")
+	if err != nil {
+		problemDetail := "[Request Body] " + err.Error()
+		rsp := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: problemDetail,
+		}
+		logger.UeauLog.Errorln(problemDetail)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(rsp.Status)))
+		c.JSON(int(rsp.Status), rsp)
+		return
+	}
+
+	// ProSeAuthenticationInfoRequest requirements check
+	missingIEList := make([]string, 0)
+	if proseAuthInfoReq.ServingNetworkName == "" {
+		missingIEList = append(missingIEList, "servingNetworkName")
+	}
+
+	if len(missingIEList) > 0 {
+		missingIEs := strings.Join(missingIEList, ", ")
+		problemDetail := models.ProblemDetails{
+			Title:  "Missing or invalid parameter",
+			Status: http.StatusBadRequest,
+			Detail: "Mandatory IE [" + missingIEs + "] is missing or invalid",
+			Cause:  "MANDATORY_IE_MISSING",
+		}
+		logger.UeauLog.Warnln("Mandatory IE [" + missingIEs + "] is missing or invalid")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	logger.UeauLog.Infoln("Handle GenerateProseAVRequest")
+
+	s.Processor().GenerateProseAVProcedure(c, proseAuthInfoReq, supiOrSuci)
 }
 
+// GetRgAuthData - Get authentication data for the FN-RG
 func (s *Server) HandleGetRgAuthData(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
+	// Validate SUPI or SUCI format
+	supiOrSuci := c.Param("supiOrSuci")
+	if !isValidSupiOrSuci(supiOrSuci) {
+		problemDetail := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: "Supi or Suci is invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UeauLog.Warnln("Supi or Suci is invalid")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	authenticatedInd, err := strconv.ParseBool(c.Query("authenticated-ind"))
+	if err != nil {
+		problemDetail := models.ProblemDetails{
+			Title:  "Missing or invalid parameter",
+			Status: http.StatusBadRequest,
+			Detail: "Mandatory IE [authenticated-ind] is missing or invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UeauLog.Warnln("Mandatory IE [authenticated-ind] is missing or invalid")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+	supportedFeatures := c.Query("supported-features")
+
+	logger.UeauLog.Infoln("Handle GetRgAuthDataRequest")
+
+	s.Processor().GetRgAuthDataProcedure(c, supiOrSuci, authenticatedInd, supportedFeatures)
 }
 
 func (s *Server) UEAUTwoLayerPathHandlerFunc(c *gin.Context) {
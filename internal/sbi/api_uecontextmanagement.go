@@ -1,7 +1,9 @@
 package sbi
 
 import (
+	"encoding/json"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -574,32 +576,254 @@ func (s *Server) HandleUpdateAmf3gppAccess(c *gin.Context) {
 
 // DeregistrationSmsfNon3gppAccess - delete SMSF registration for non 3GPP access
 func (s *Server) HandleDeregistrationSmsfNon3gppAccess(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
+	logger.UecmLog.Infof("Handle DeregistrationSmsfNon3gppAccess")
+
+	ueID := c.Param("ueId")
+	// TS 29.503 5.3.2.4.6
+	// Validate SUPI format the UE ID (SUPI) shall be in the format defined in 3GPP TS 23.003 & 29.571
+	valid := validator.IsValidSupi(ueID)
+	if !valid {
+		problemDetail := models.ProblemDetails{
+			Title:  "Invalid ueID format",
+			Status: http.StatusBadRequest,
+			Detail: "The ueID format is invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UecmLog.Warnf("Deregistration Reject: Invalid ueID format [%s]", ueID)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	s.Processor().DeregistrationSmsfNon3gppAccessProcedure(c, ueID)
 }
 
 // DeregistrationSmsf3gppAccess - delete the SMSF registration for 3GPP access
 func (s *Server) HandleDeregistrationSmsf3gppAccess(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
+	logger.UecmLog.Infof("Handle DeregistrationSmsf3gppAccess")
+
+	ueID := c.Param("ueId")
+	// TS 29.503 5.3.2.4.5
+	// Validate SUPI format the UE ID (SUPI) shall be in the format defined in 3GPP TS 23.003 & 29.571
+	valid := validator.IsValidSupi(ueID)
+	if !valid {
+		problemDetail := models.ProblemDetails{
+			Title:  "Invalid ueID format",
+			Status: http.StatusBadRequest,
+			Detail: "The ueID format is invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UecmLog.Warnf("Deregistration Reject: Invalid ueID format [%s]", ueID)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	s.Processor().DeregistrationSmsf3gppAccessProcedure(c, ueID)
 }
 
 // GetSmsfNon3gppAccess - retrieve the SMSF registration for non-3GPP access information
 func (s *Server) HandleGetSmsfNon3gppAccess(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
+	logger.UecmLog.Infof("Handle GetSmsfNon3gppAccess")
+
+	ueID := c.Param("ueId")
+	// TS 29.503 5.3.2.5.6
+	// Validate SUPI and GPSI format the UE ID (SUPI or GPSI) shall be in the format defined in 3GPP TS 23.003 & 29.571
+	valid := validator.IsValidSupi(ueID) || validator.IsValidGpsi(ueID)
+	if !valid {
+		problemDetail := models.ProblemDetails{
+			Title:  "Invalid ueID format",
+			Status: http.StatusBadRequest,
+			Detail: "The ueID format is invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UecmLog.Warnf("Registration Reject: Invalid ueID format [%s]", ueID)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+	supportedFeatures := c.Query("supported-features")
+
+	s.Processor().GetSmsfNon3gppAccessProcedure(c, ueID, supportedFeatures)
 }
 
 // RegistrationSmsfNon3gppAccess - register as SMSF for non-3GPP access
 func (s *Server) HandleRegistrationSmsfNon3gppAccess(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
+	var smsfRegistration models.Udm_UECM_SmsfRegistration
+
+	ueID := c.Param("ueId")
+	// TS 29.503 5.3.2.2.6
+	// Validate SUPI format the UE ID (SUPI) shall be in the format defined in 3GPP TS 23.003 & 29.571
+	valid := validator.IsValidSupi(ueID)
+	if !valid {
+		problemDetail := models.ProblemDetails{
+			Title:  "Invalid ueID format",
+			Status: http.StatusBadRequest,
+			Detail: "The ueID format is invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UecmLog.Warnf("Registration Reject: Invalid ueID format [%s]", ueID)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	requestBody, err := c.GetRawData()
+	if err != nil {
+		problemDetail := models.ProblemDetails{
+			Title:  "System failure",
+			Status: http.StatusInternalServerError,
+			Detail: err.Error(),
+			Cause:  "SYSTEM_FAILURE",
+		}
+		logger.UecmLog.Errorf("Get Request Body error: %+v", err)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetail.Cause)
+		c.JSON(http.StatusInternalServerError, problemDetail)
+		return
+	}
+
+	err = openapi.Deserialize(&smsfRegistration, requestBody, "application/json")
+	if err != nil {
+		problemDetail := "[Request Body] " + err.Error()
+		rsp := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: problemDetail,
+		}
+		logger.UecmLog.Errorln(problemDetail)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(rsp.Status)))
+		c.JSON(int(rsp.Status), rsp)
+		return
+	}
+
+	// TS 29.503 6.2.6.2.5 requirements check
+	missingIEList := make([]string, 0)
+	if smsfRegistration.SmsfInstanceId == "" {
+		missingIEList = append(missingIEList, "SmsfInstanceId")
+	}
+	if smsfRegistration.PlmnId == nil {
+		missingIEList = append(missingIEList, "PlmnId")
+	}
+
+	if len(missingIEList) > 0 {
+		missingIEs := strings.Join(missingIEList, ", ")
+		problemDetail := models.ProblemDetails{
+			Title:  "Missing or invalid parameter",
+			Status: http.StatusBadRequest,
+			Detail: "Mandatory IE [" + missingIEs + "] is missing or invalid",
+			Cause:  "MANDATORY_IE_MISSING",
+		}
+		logger.UecmLog.Warnln("Mandatory IE [" + missingIEs + "] is missing or invalid")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	logger.UecmLog.Infof("Handle RegistrationSmsfNon3gppAccess")
+
+	s.Processor().RegistrationSmsfNon3gppAccessProcedure(c, smsfRegistration, ueID)
 }
 
 // UpdateSMSFReg3GPP - register as SMSF for 3GPP access
 func (s *Server) HandleUpdateSMSFReg3GPP(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
+	var smsfRegistration models.Udm_UECM_SmsfRegistration
+
+	ueID := c.Param("ueId")
+	// TS 29.503 5.3.2.2.5
+	// Validate SUPI format the UE ID (SUPI) shall be in the format defined in 3GPP TS 23.003 & 29.571
+	valid := validator.IsValidSupi(ueID)
+	if !valid {
+		problemDetail := models.ProblemDetails{
+			Title:  "Invalid ueID format",
+			Status: http.StatusBadRequest,
+			Detail: "The ueID format is invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UecmLog.Warnf("Registration Reject: Invalid ueID format [%s]", ueID)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	requestBody, err := c.GetRawData()
+	if err != nil {
+		problemDetail := models.ProblemDetails{
+			Title:  "System failure",
+			Status: http.StatusInternalServerError,
+			Detail: err.Error(),
+			Cause:  "SYSTEM_FAILURE",
+		}
+		logger.UecmLog.Errorf("Get Request Body error: %+v", err)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetail.Cause)
+		c.JSON(http.StatusInternalServerError, problemDetail)
+		return
+	}
+
+	err = openapi.Deserialize(&smsfRegistration, requestBody, "application/json")
+	if err != nil {
+		problemDetail := "[Request Body] " + err.Error()
+		rsp := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: problemDetail,
+		}
+		logger.UecmLog.Errorln(problemDetail)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(rsp.Status)))
+		c.JSON(int(rsp.Status), rsp)
+		return
+	}
+
+	// TS 29.503 6.2.6.2.5 requirements check
+	missingIEList := make([]string, 0)
+	if smsfRegistration.SmsfInstanceId == "" {
+		missingIEList = append(missingIEList, "SmsfInstanceId")
+	}
+	if smsfRegistration.PlmnId == nil {
+		missingIEList = append(missingIEList, "PlmnId")
+	}
+
+	if len(missingIEList) > 0 {
+		missingIEs := strings.Join(missingIEList, ", ")
+		problemDetail := models.ProblemDetails{
+			Title:  "Missing or invalid parameter",
+			Status: http.StatusBadRequest,
+			Detail: "Mandatory IE [" + missingIEs + "] is missing or invalid",
+			Cause:  "MANDATORY_IE_MISSING",
+		}
+		logger.UecmLog.Warnln("Mandatory IE [" + missingIEs + "] is missing or invalid")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	logger.UecmLog.Infof("Handle UpdateSMSFReg3GPP")
+
+	s.Processor().RegistrationSmsf3gppAccessProcedure(c, smsfRegistration, ueID)
 }
 
 // GetSmsf3gppAccess - retrieve the SMSF registration for 3GPP access information
 func (s *Server) HandleGetSmsf3gppAccess(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
+	logger.UecmLog.Infof("Handle GetSmsf3gppAccess")
+
+	ueID := c.Param("ueId")
+	// TS 29.503 5.3.2.5.5
+	// Validate SUPI and GPSI format the UE ID (SUPI or GPSI) shall be in the format defined in 3GPP TS 23.003 & 29.571
+	valid := validator.IsValidSupi(ueID) || validator.IsValidGpsi(ueID)
+	if !valid {
+		problemDetail := models.ProblemDetails{
+			Title:  "Invalid ueID format",
+			Status: http.StatusBadRequest,
+			Detail: "The ueID format is invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UecmLog.Warnf("Registration Reject: Invalid ueID format [%s]", ueID)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+	supportedFeatures := c.Query("supported-features")
+
+	s.Processor().GetSmsf3gppAccessProcedure(c, ueID, supportedFeatures)
 }
 
 // DeregistrationSmfRegistrations - delete an SMF registration
@@ -765,27 +989,339 @@ func (s *Server) HandleGetAmf3gppAccess(c *gin.Context) {
 	s.Processor().GetAmf3gppAccessProcedure(c, ueID, supportedFeatures)
 }
 
+// DeregAMF - trigger the deregistration of the AMF serving the UE over 3GPP access
 func (s *Server) HandleDeregAMF(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
+	var amfDeregInfo models.Udm_UECM_AmfDeregInfo
+
+	ueID := c.Param("ueId")
+	// Validate SUPI format the UE ID (SUPI) shall be in the format defined in 3GPP TS 23.003 & 29.571
+	valid := validator.IsValidSupi(ueID)
+	if !valid {
+		problemDetail := models.ProblemDetails{
+			Title:  "Invalid ueID format",
+			Status: http.StatusBadRequest,
+			Detail: "The ueID format is invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UecmLog.Warnf("DeregAMF Reject: Invalid ueID format [%s]", ueID)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	requestBody, err := c.GetRawData()
+	if err != nil {
+		problemDetail := models.ProblemDetails{
+			Title:  "System failure",
+			Status: http.StatusInternalServerError,
+			Detail: err.Error(),
+			Cause:  "SYSTEM_FAILURE",
+		}
+		logger.UecmLog.Errorf("Get Request Body error: %+v", err)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetail.Cause)
+		c.JSON(http.StatusInternalServerError, problemDetail)
+		return
+	}
+
+	err = openapi.Deserialize(&amfDeregInfo, requestBody, "application/json")
+	if err != nil {
+		problemDetail := "[Request Body] " + err.Error()
+		rsp := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: problemDetail,
+		}
+		logger.UecmLog.Errorln(problemDetail)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(rsp.Status)))
+		c.JSON(int(rsp.Status), rsp)
+		return
+	}
+
+	// AmfDeregInfo requirements check
+	if amfDeregInfo.DeregReason == "" {
+		problemDetail := models.ProblemDetails{
+			Title:  "Missing or invalid parameter",
+			Status: http.StatusBadRequest,
+			Detail: "Mandatory IE DeregReason is missing or invalid",
+			Cause:  "MANDATORY_IE_MISSING",
+		}
+		logger.UecmLog.Warnln("Mandatory IE DeregReason is missing or invalid")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	logger.UecmLog.Infof("Handle DeregAMF")
+
+	s.Processor().DeregAMFProcedure(c, amfDeregInfo, ueID)
 }
 
-func (s *Server) HandleGetIpSmGwRegistration(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
+// UpdateSmfRegistration - update the SMF registration of a PDU session of the UE
+func (s *Server) HandleUpdateSmfRegistration(c *gin.Context) {
+	var smfRegistrationModification models.Udm_UECM_SmfRegistrationModification
+
+	ueID := c.Param("ueId")
+	// Validate SUPI format the UE ID (SUPI) shall be in the format defined in 3GPP TS 23.003 & 29.571
+	valid := validator.IsValidSupi(ueID)
+	if !valid {
+		problemDetail := models.ProblemDetails{
+			Title:  "Invalid ueID format",
+			Status: http.StatusBadRequest,
+			Detail: "The ueID format is invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UecmLog.Warnf("UpdateSmfRegistration Reject: Invalid ueID format [%s]", ueID)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+	pduSessionID := c.Param("pduSessionId")
+	// TS 29.571 5.4.2 valid PDU Session ID is an integer in the range 0 to 255
+	if !validator.IsValidPduSessionID(pduSessionID) {
+		problemDetail := models.ProblemDetails{
+			Title:  "Missing or invalid parameter",
+			Status: http.StatusBadRequest,
+			Detail: "Mandatory IE PduSessionId is missing or invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UecmLog.Warnln("Mandatory IE PduSessionId is missing or invalid")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	requestBody, err := c.GetRawData()
+	if err != nil {
+		problemDetail := models.ProblemDetails{
+			Title:  "System failure",
+			Status: http.StatusInternalServerError,
+			Detail: err.Error(),
+			Cause:  "SYSTEM_FAILURE",
+		}
+		logger.UecmLog.Errorf("Get Request Body error: %+v", err)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetail.Cause)
+		c.JSON(http.StatusInternalServerError, problemDetail)
+		return
+	}
+
+	err = openapi.Deserialize(&smfRegistrationModification, requestBody, "application/json")
+	if err != nil {
+		problemDetail := "[Request Body] " + err.Error()
+		rsp := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: problemDetail,
+		}
+		logger.UecmLog.Errorln(problemDetail)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(rsp.Status)))
+		c.JSON(int(rsp.Status), rsp)
+		return
+	}
+
+	// SmfRegistrationModification requirements check
+	if smfRegistrationModification.SmfInstanceId == "" {
+		problemDetail := models.ProblemDetails{
+			Title:  "Missing or invalid parameter",
+			Status: http.StatusBadRequest,
+			Detail: "Mandatory IE SmfInstanceId is missing or invalid",
+			Cause:  "MANDATORY_IE_MISSING",
+		}
+		logger.UecmLog.Warnln("Mandatory IE SmfInstanceId is missing or invalid")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	logger.UecmLog.Infof("Handle UpdateSmfRegistration")
+
+	s.Processor().UpdateSmfRegistrationProcedure(c, smfRegistrationModification, ueID, pduSessionID)
 }
 
-func (s *Server) HandleGetLocationInfo(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
+// GetRegistrations - retrieve the registrations of the NFs serving the UE
+func (s *Server) HandleGetRegistrations(c *gin.Context) {
+	logger.UecmLog.Infof("Handle GetRegistrations")
+
+	ueID := c.Param("ueId")
+	// Validate SUPI format the UE ID (SUPI) shall be in the format defined in 3GPP TS 23.003 & 29.571
+	valid := validator.IsValidSupi(ueID)
+	if !valid {
+		problemDetail := models.ProblemDetails{
+			Title:  "Invalid ueID format",
+			Status: http.StatusBadRequest,
+			Detail: "The ueID format is invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UecmLog.Warnf("GetRegistrations Reject: Invalid ueID format [%s]", ueID)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	var datasetNames []models.Udm_UECM_RegistrationDataSetName
+	for _, name := range strings.Split(c.Query("registration-dataset-names"), ",") {
+		datasetName := models.Udm_UECM_RegistrationDataSetName(strings.TrimSpace(name))
+		switch datasetName {
+		case models.Udm_UECM_RegistrationDataSetName_AMF_3_GPP,
+			models.Udm_UECM_RegistrationDataSetName_AMF_NON_3_GPP,
+			models.Udm_UECM_RegistrationDataSetName_SMF_PDU_SESSIONS,
+			models.Udm_UECM_RegistrationDataSetName_SMSF_3_GPP,
+			models.Udm_UECM_RegistrationDataSetName_SMSF_NON_3_GPP,
+			models.Udm_UECM_RegistrationDataSetName_IP_SM_GW,
+			models.Udm_UECM_RegistrationDataSetName_NWDAF:
+			datasetNames = append(datasetNames, datasetName)
+		default:
+			problemDetail := models.ProblemDetails{
+				Title:  "Missing or invalid parameter",
+				Status: http.StatusBadRequest,
+				Detail: "Mandatory IE registration-dataset-names is missing or invalid",
+				Cause:  "MANDATORY_IE_INCORRECT",
+				InvalidParams: []models.InvalidParam{{
+					Param:  "query registration-dataset-names",
+					Reason: "unknown registration dataset name [" + string(datasetName) + "]",
+				}},
+			}
+			logger.UecmLog.Warnf("GetRegistrations Reject: invalid registration dataset name [%s]", datasetName)
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+			c.JSON(int(problemDetail.Status), problemDetail)
+			return
+		}
+	}
+	singleNssai, problemDetails := s.getSingleNssaiStruct(c.Request.URL.Query())
+	if problemDetails != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+	dnn := c.Query("dnn")
+	supportedFeatures := c.Query("supported-features")
+
+	s.Processor().GetRegistrationsProcedure(c, ueID, datasetNames, singleNssai, dnn, supportedFeatures)
 }
 
-func (s *Server) HandleGetNwdafRegistration(c *gin.Context) {
+// getSingleNssaiStruct returns the S-NSSAI of the single-nssai query parameter, nil if absent
+func (s *Server) getSingleNssaiStruct(
+	queryParameters url.Values,
+) (singleNssai *models.Snssai, problemDetails *models.ProblemDetails) {
+	values, exists := queryParameters["single-nssai"]
+	if !exists {
+		return nil, nil
+	}
+	if len(values) == 0 || strings.TrimSpace(values[0]) == "" {
+		problemDetails = &models.ProblemDetails{
+			Title:  "Invalid Parameter",
+			Status: http.StatusBadRequest,
+			Cause:  "OPTIONAL_QUERY_PARAM_INCORRECT",
+			InvalidParams: []models.InvalidParam{{
+				Param:  "query single-nssai",
+				Reason: "cannot be empty",
+			}},
+		}
+		return nil, problemDetails
+	}
+
+	singleNssai = &models.Snssai{}
+	err := json.Unmarshal([]byte(values[0]), singleNssai)
+	if err != nil {
+		logger.UecmLog.Warnln("Unmarshal Error in single-nssai: ", err)
+		problemDetails = &models.ProblemDetails{
+			Title:  "Invalid Parameter",
+			Status: http.StatusBadRequest,
+			Cause:  "OPTIONAL_QUERY_PARAM_INCORRECT",
+			InvalidParams: []models.InvalidParam{{
+				Param:  "query single-nssai",
+				Reason: "must be a valid Snssai JSON object",
+			}},
+		}
+		return nil, problemDetails
+	}
+	return singleNssai, nil
+}
+
+// GetSmfRegistration - retrieve the SMF registrations of the UE
+func (s *Server) HandleGetSmfRegistration(c *gin.Context) {
+	logger.UecmLog.Infof("Handle GetSmfRegistration")
+
+	ueID := c.Param("ueId")
+	// Validate SUPI format the UE ID (SUPI) shall be in the format defined in 3GPP TS 23.003 & 29.571
+	valid := validator.IsValidSupi(ueID)
+	if !valid {
+		problemDetail := models.ProblemDetails{
+			Title:  "Invalid ueID format",
+			Status: http.StatusBadRequest,
+			Detail: "The ueID format is invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UecmLog.Warnf("GetSmfRegistration Reject: Invalid ueID format [%s]", ueID)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+	singleNssai, problemDetails := s.getSingleNssaiStruct(c.Request.URL.Query())
+	if problemDetails != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+	dnn := c.Query("dnn")
+	supportedFeatures := c.Query("supported-features")
+
+	s.Processor().GetSmfRegistrationProcedure(c, ueID, singleNssai, dnn, supportedFeatures)
+}
+
+// RetrieveSmfRegistration - retrieve the SMF registration of a PDU session of the UE
+func (s *Server) HandleRetrieveSmfRegistration(c *gin.Context) {
+	logger.UecmLog.Infof("Handle RetrieveSmfRegistration")
+
+	ueID := c.Param("ueId")
+	// Validate SUPI format the UE ID (SUPI) shall be in the format defined in 3GPP TS 23.003 & 29.571
+	valid := validator.IsValidSupi(ueID)
+	if !valid {
+		problemDetail := models.ProblemDetails{
+			Title:  "Invalid ueID format",
+			Status: http.StatusBadRequest,
+			Detail: "The ueID format is invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UecmLog.Warnf("RetrieveSmfRegistration Reject: Invalid ueID format [%s]", ueID)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+	pduSessionID := c.Param("pduSessionId")
+	// TS 29.571 5.4.2 valid PDU Session ID is an integer in the range 0 to 255
+	if !validator.IsValidPduSessionID(pduSessionID) {
+		problemDetail := models.ProblemDetails{
+			Title:  "Missing or invalid parameter",
+			Status: http.StatusBadRequest,
+			Detail: "Mandatory IE PduSessionId is missing or invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UecmLog.Warnln("Mandatory IE PduSessionId is missing or invalid")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+	singleNssai, problemDetails := s.getSingleNssaiStruct(c.Request.URL.Query())
+	if problemDetails != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+	dnn := c.Query("dnn")
+	supportedFeatures := c.Query("supported-features")
+
+	s.Processor().RetrieveSmfRegistrationProcedure(c, ueID, pduSessionID, singleNssai, dnn, supportedFeatures)
+}
+
+func (s *Server) HandleGetIpSmGwRegistration(c *gin.Context) {
 	c.JSON(http.StatusNotImplemented, gin.H{})
 }
 
-func (s *Server) HandleGetRegistrations(c *gin.Context) {
+func (s *Server) HandleGetLocationInfo(c *gin.Context) {
 	c.JSON(http.StatusNotImplemented, gin.H{})
 }
 
-func (s *Server) HandleGetSmfRegistration(c *gin.Context) {
+func (s *Server) HandleGetNwdafRegistration(c *gin.Context) {
 	c.JSON(http.StatusNotImplemented, gin.H{})
 }
 
@@ -809,10 +1345,6 @@ func (s *Server) HandlePeiUpdate(c *gin.Context) {
 	c.JSON(http.StatusNotImplemented, gin.H{})
 }
 
-func (s *Server) HandleRetrieveSmfRegistration(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
-}
-
 func (s *Server) HandleSendRoutingInfoSm(c *gin.Context) {
 	c.JSON(http.StatusNotImplemented, gin.H{})
 }
@@ -828,7 +1360,3 @@ func (s *Server) HandleUpdateNwdafRegistration(c *gin.Context) {
 func (s *Server) HandleUpdateRoamingInformation(c *gin.Context) {
 	c.JSON(http.StatusNotImplemented, gin.H{})
 }
-
-func (s *Server) HandleUpdateSmfRegistration(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
-}
@@ -4,6 +4,12 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi"
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/udm/internal/logger"
+	"github.com/free5gc/udm/pkg/suci"
+	"github.com/free5gc/util/metrics/sbi"
 )
 
 func (s *Server) getUEIDRoutes() []Route {
@@ -24,6 +30,53 @@ func (s *Server) getUEIDRoutes() []Route {
 	}
 }
 
+// Deconceal - Deconceal the SUCI to the SUPI
 func (s *Server) HandleDeconceal(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{})
+	var deconcealReqData models.Udm_UEID_DeconcealReqData
+
+	requestBody, err := c.GetRawData()
+	if err != nil {
+		problemDetail := models.ProblemDetails{
+			Title:  "System failure",
+			Status: http.StatusInternalServerError,
+			Detail: err.Error(),
+			Cause:  "SYSTEM_FAILURE",
+		}
+		logger.UeidLog.Errorf("Get Request Body error: %+v", err)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetail.Cause)
+		c.JSON(http.StatusInternalServerError, problemDetail)
+		return
+	}
+
+	err = openapi.Deserialize(&deconcealReqData, requestBody, "application/json")
+	if err != nil {
+		problemDetail := "[Request Body] " + err.Error()
+		rsp := models.ProblemDetails{
+			Title:  "Malformed request syntax",
+			Status: http.StatusBadRequest,
+			Detail: problemDetail,
+		}
+		logger.UeidLog.Errorln(problemDetail)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(rsp.Status)))
+		c.JSON(int(rsp.Status), rsp)
+		return
+	}
+
+	// only a SUCI is deconcealed, a SUPI is not accepted in its place
+	if !suci.IsValidSuci(deconcealReqData.Suci) {
+		problemDetail := models.ProblemDetails{
+			Title:  "Missing or invalid parameter",
+			Status: http.StatusBadRequest,
+			Detail: "Mandatory IE [suci] is missing or invalid",
+			Cause:  "MANDATORY_IE_INCORRECT",
+		}
+		logger.UeidLog.Warnln("Mandatory IE [suci] is missing or invalid")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(int(problemDetail.Status)))
+		c.JSON(int(problemDetail.Status), problemDetail)
+		return
+	}
+
+	logger.UeidLog.Infoln("Handle DeconcealRequest")
+
+	s.Processor().DeconcealProcedure(c, deconcealReqData)
 }
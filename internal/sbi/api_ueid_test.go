@@ -0,0 +1,60 @@
+package sbi
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/openapi/models"
+	udm_context "github.com/free5gc/udm/internal/context"
+	"github.com/free5gc/udm/internal/sbi/consumer"
+	"github.com/free5gc/udm/internal/sbi/processor"
+)
+
+func TestIsValidSupiOrSuci(t *testing.T) {
+	testCases := []struct {
+		supiOrSuci string
+		valid      bool
+	}{
+		{"imsi-208930000000001", true},
+		{"suci-0-208-93-0000-0-0-0000000001", true},
+		{"suci-0-208-93-0000-1-100-0011", true},
+		{"suci-0-208-93-0000-1-255-0011", true},
+		{"suci-1-example.com-0-0-0-user", true},
+		{"suci-0-208-93-0000-1-256-0011", false},
+		{"suci-0-208-93-0000-1-0100-0011", false},
+		{"not-a-suci", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.supiOrSuci, func(t *testing.T) {
+			require.Equal(t, tc.valid, isValidSupiOrSuci(tc.supiOrSuci))
+		})
+	}
+}
+
+func TestHandleDeconcealAcceptsThreeDigitKeyId(t *testing.T) {
+	udmCtx := udm_context.GetSelf()
+	testApp := &traceDataTestApp{udmCtx: udmCtx}
+	var err error
+	testApp.consumer, err = consumer.NewConsumer(testApp)
+	require.NoError(t, err)
+	testApp.processor, err = processor.NewProcessor(testApp)
+	require.NoError(t, err)
+	server := &Server{ServerUdm: testApp}
+
+	body, err := json.Marshal(models.Udm_UEID_DeconcealReqData{Suci: "suci-0-208-93-0000-1-100-0011"})
+	require.NoError(t, err)
+	recorder, c := newSDMTestContext(t, http.MethodPost, "/deconceal", string(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	server.HandleDeconceal(c)
+
+	// the SUCI passes the syntax check and is rejected for the key ID no profile holds
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+	var problemDetails models.ProblemDetails
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problemDetails))
+	require.Equal(t, "UNKNOWN_KEY_ID", problemDetails.Cause)
+}
@@ -211,6 +211,8 @@ func (s *nnrfService) buildNfProfile(udmContext *udm_context.UDMContext) (
 		profile.NfServices = append(profile.NfServices, nfService)
 	}
 	profile.UdmInfo = &models.Nrf_NFMgmt_UdmInfo{
+		GroupId:           udmContext.GroupId,
+		RoutingIndicators: udmContext.RoutingIndicators,
 		// Todo
 		// SupiRanges: &[]models.Nrf_NFMgmt_SupiRange{
 		// 	{
@@ -0,0 +1,53 @@
+package processor
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	udm_context "github.com/free5gc/udm/internal/context"
+)
+
+// authFailureStatus reports the authentication failure counters of a UE
+type authFailureStatus struct {
+	Supi string `json:"supi"`
+	udm_context.AuthFailureCounters
+	LockedOut bool `json:"lockedOut"`
+}
+
+func newAuthFailureStatus(supi string, counters udm_context.AuthFailureCounters, now time.Time) authFailureStatus {
+	return authFailureStatus{
+		Supi:                supi,
+		AuthFailureCounters: counters,
+		LockedOut:           counters.LockedUntil != nil && now.Before(*counters.LockedUntil),
+	}
+}
+
+// GetAuthFailuresProcedure lists the UEs with authentication failures or a lockout
+func (p *Processor) GetAuthFailuresProcedure(c *gin.Context) {
+	now := time.Now()
+	statuses := make([]authFailureStatus, 0)
+	for supi, counters := range p.Context().GetAllAuthFailures() {
+		status := newAuthFailureStatus(supi, counters, now)
+		if status.ResyncFailures > 0 || status.ConfirmationFailures > 0 || status.LockedOut {
+			statuses = append(statuses, status)
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Supi < statuses[j].Supi
+	})
+
+	c.JSON(http.StatusOK, statuses)
+}
+
+func (p *Processor) GetAuthFailureProcedure(c *gin.Context, supi string) {
+	c.JSON(http.StatusOK, newAuthFailureStatus(supi, p.Context().GetAuthFailures(supi), time.Now()))
+}
+
+// DeleteAuthFailureProcedure lets the operator clear the counters and the lockout of a UE
+func (p *Processor) DeleteAuthFailureProcedure(c *gin.Context, supi string) {
+	p.Context().ClearAuthFailures(supi)
+	c.Status(http.StatusNoContent)
+}
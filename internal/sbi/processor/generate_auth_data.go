@@ -1,13 +1,15 @@
 package processor
 
 import (
+	"context"
 	cryptoRand "crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"math/big"
 	"math/rand"
 	"net/http"
 	"reflect"
+	"slices"
 	"strings"
 	"time"
 
@@ -16,32 +18,41 @@ import (
 	"github.com/free5gc/openapi"
 	"github.com/free5gc/openapi/models"
 	Nudr_DataRepository "github.com/free5gc/openapi/udr/DR"
+	udm_context "github.com/free5gc/udm/internal/context"
 	"github.com/free5gc/udm/internal/logger"
-	"github.com/free5gc/udm/pkg/suci"
+	"github.com/free5gc/udm/pkg/aka"
+	"github.com/free5gc/udm/pkg/servingnetwork"
+	"github.com/free5gc/udm/pkg/sqn"
 	"github.com/free5gc/util/metrics/sbi"
 	"github.com/free5gc/util/milenage"
 	"github.com/free5gc/util/ueauth"
 )
 
 const (
-	SqnMAx    int64 = 0xFFFFFFFFFFFF
-	ind       int64 = 32
-	keyStrLen int   = 32
-	opStrLen  int   = 32
-	opcStrLen int   = 32
+	keyStrLen  int = 32
+	opcStrLen  int = 32
+	topcStrLen int = 64
 )
 
 const (
 	authenticationRejected string = "AUTHENTICATION_REJECTED"
-	resyncAMF              string = "0000"
+	// the UE is temporarily rejected after repeated authentication failures
+	authenticationLockedOut string = "AUTHENTICATION_LOCKED_OUT"
+	// the serving network name is malformed or the subscriber may not be served there
+	servingNetworkNotAuthorized string = "SERVING_NETWORK_NOT_AUTHORIZED"
+	// another UDM instance kept updating the SQN of the UE, the vectors are not issued
+	sqnUpdateConflict string = "SQN_UPDATE_CONFLICT"
+	resyncAMF         string = "0000"
+	// the number of times the SQN update is tried before giving up on a conflict
+	maxSqnUpdateAttempts = 3
 )
 
-func (p *Processor) aucSQN(opc, k, auts, rand []byte) ([]byte, []byte, error) {
+func (p *Processor) aucSQN(alg aka.Algorithm, auts, rand []byte) ([]byte, []byte, error) {
 	// Use ValidateAUTS to verify AUTS and extract SQNms
 	// This function internally:
 	// 1. Uses AK* (f5*) to de-conceal SQNms from AUTS
 	// 2. Computes MAC-S with AMF=0x0000 and verifies it
-	SQNms, err := milenage.ValidateAUTS(opc, k, rand, auts)
+	SQNms, err := alg.ValidateAUTS(rand, auts)
 	if err != nil {
 		logger.UeauLog.Errorln("aucSQN ValidateAUTS err:", err)
 		return nil, nil, err
@@ -56,6 +67,22 @@ func (p *Processor) aucSQN(opc, k, auts, rand []byte) ([]byte, []byte, error) {
 	return SQNms, macS, err
 }
 
+func (p *Processor) recordResyncFailure(supi string) {
+	if p.Context().RecordResyncFailure(supi) {
+		logger.UeauLog.Warnf("Authentication of supi[%s] is locked out after repeated re-synchronization failures",
+			supi)
+	}
+}
+
+// sqnScheme returns the configured SQN management scheme of TS 33.102 Annex C, the plain
+// SQN counter when none is configured
+func (p *Processor) sqnScheme() sqn.Scheme {
+	if scheme := p.Context().SqnScheme; scheme != nil {
+		return *scheme
+	}
+	return sqn.PlainScheme
+}
+
 func (p *Processor) strictHex(ss string, n int) string {
 	l := len(ss)
 	if l < n {
@@ -103,14 +130,23 @@ func (p *Processor) ConfirmAuthDataProcedure(c *gin.Context,
 		return
 	}
 
+	// TS 29.503 6.3.3.2.3: the created auth event resource is addressed by authEventId
+	c.Header("Location", udm_context.GetAuthEventLocationURI(supi, udm_context.AuthEventId(supi, &authEvent)))
+
+	if authEvent.Success {
+		p.Context().ResetAuthFailures(supi)
+	} else if p.Context().RecordConfirmationFailure(supi) {
+		logger.UeauLog.Warnf("Authentication of supi[%s] is locked out after repeated failed authentications", supi)
+	}
+
 	// AuthEvent in response body is optional
 	c.JSON(http.StatusCreated, gin.H{})
 }
 
-func (p *Processor) GenerateAuthDataProcedure(
-	c *gin.Context,
-	authInfoRequest models.Udm_UEAU_AuthenticationInfoRequest,
-	supiOrSuci string,
+func (p *Processor) DeleteAuthProcedure(c *gin.Context,
+	authEvent models.Udm_UEAU_AuthEvent,
+	supi string,
+	authEventId string,
 ) {
 	ctx, pd, err := p.Context().GetTokenCtx(models.Nrf_NFMgmt_ServiceName_NUDR_DR, models.Nrf_NFMgmt_NFType_UDR)
 	if err != nil {
@@ -118,37 +154,85 @@ func (p *Processor) GenerateAuthDataProcedure(
 		c.JSON(int(pd.Status), pd)
 		return
 	}
-	logger.UeauLog.Traceln("In GenerateAuthDataProcedure")
 
-	response := &models.Udm_UEAU_AuthenticationInfoResult{}
-	rand.New(rand.NewSource(time.Now().UnixNano()))
-	supi, err := suci.ToSupi(supiOrSuci, p.Context().SuciProfiles)
+	client, err := p.Consumer().CreateUDMClientToUDR(supi)
 	if err != nil {
-		problemDetails := &models.ProblemDetails{
-			Status: http.StatusForbidden,
-			Cause:  authenticationRejected,
-			Detail: err.Error(),
-		}
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
 
-		logger.UeauLog.Errorln("suciToSupi error: ", err.Error())
+	var queryAuthStatusRequest Nudr_DataRepository.QueryAuthenticationStatusRequest
+	queryAuthStatusRequest.UeId = &supi
+	authStatus, err := client.AuthEventDocumentApi.QueryAuthenticationStatus(ctx, &queryAuthStatusRequest)
+	if err != nil {
+		apiError, ok := err.(openapi.GenericOpenAPIError)
+		if ok {
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(apiError.ErrorStatus))
+			c.Data(apiError.ErrorStatus, "application/json", apiError.RawBody)
+			return
+		}
+		logger.UeauLog.Errorln("DeleteAuth err:", err.Error())
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
 		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
 		c.JSON(int(problemDetails.Status), problemDetails)
 		return
 	}
 
-	logger.UeauLog.Tracef("supi conversion => [%s]", supi)
+	if authStatus == nil || authStatus.Udm_UEAU_AuthEvent == nil ||
+		authStatus.Udm_UEAU_AuthEvent.ServingNetworkName != authEvent.ServingNetworkName {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusNotFound,
+			Cause:  "DATA_NOT_FOUND",
+			Detail: "No authentication event stored for serving network " + authEvent.ServingNetworkName,
+		}
+		logger.UeauLog.Warnf("DeleteAuth: no authentication event for supi [%s]", supi)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+	if udm_context.AuthEventId(supi, authStatus.Udm_UEAU_AuthEvent) != authEventId {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusNotFound,
+			Cause:  "DATA_NOT_FOUND",
+			Detail: "authEventId " + authEventId + " does not match any authentication event",
+		}
+		logger.UeauLog.Warnf("DeleteAuth: unknown authEventId [%s] for supi [%s]", authEventId, supi)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
 
-	client, err := p.Consumer().CreateUDMClientToUDR(supi)
+	var deleteAuthStatusRequest Nudr_DataRepository.DeleteAuthenticationStatusRequest
+	deleteAuthStatusRequest.UeId = &supi
+	_, err = client.AuthEventDocumentApi.DeleteAuthenticationStatus(ctx, &deleteAuthStatusRequest)
 	if err != nil {
+		apiError, ok := err.(openapi.GenericOpenAPIError)
+		if ok {
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(apiError.ErrorStatus))
+			c.Data(apiError.ErrorStatus, "application/json", apiError.RawBody)
+			return
+		}
+		logger.UeauLog.Errorln("DeleteAuth err:", err.Error())
 		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
 		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
 		c.JSON(int(problemDetails.Status), problemDetails)
 		return
 	}
-	var queryAuthSubsDataRequest Nudr_DataRepository.QueryAuthSubsDataRequest
-	queryAuthSubsDataRequest.UeId = &supi
 
-	authSubs, err := client.AuthenticationDataDocumentApi.QueryAuthSubsData(ctx, &queryAuthSubsDataRequest)
+	c.Status(http.StatusNoContent)
+}
+
+// queryAuthSubscription fetches the authentication subscription of the UE from the UDR.
+// On failure the error response is already written to c and nil is returned.
+func (p *Processor) queryAuthSubscription(
+	c *gin.Context,
+	ctx context.Context,
+	client *Nudr_DataRepository.APIClient,
+	supi string,
+) *models.Udr_DR_AuthenticationSubscription {
+	authSubscription, err := p.readAuthSubscription(ctx, client, supi)
 	if err != nil {
 		logger.ProcLog.Errorf("Error on QueryAuthSubsData: %+v", err)
 		apiError, ok := err.(openapi.GenericOpenAPIError)
@@ -161,20 +245,104 @@ func (p *Processor) GenerateAuthDataProcedure(
 			default:
 				logger.UeauLog.Errorln("Return from UDR QueryAuthSubsData error")
 			}
-			return
+			return nil
 		}
 		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
 		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
 		c.JSON(int(problemDetails.Status), problemDetails)
-		return
+		return nil
+	}
+	return authSubscription
+}
+
+// readAuthSubscription fetches the authentication subscription of the UE from the UDR
+func (p *Processor) readAuthSubscription(
+	ctx context.Context,
+	client *Nudr_DataRepository.APIClient,
+	supi string,
+) (*models.Udr_DR_AuthenticationSubscription, error) {
+	var queryAuthSubsDataRequest Nudr_DataRepository.QueryAuthSubsDataRequest
+	queryAuthSubsDataRequest.UeId = &supi
+
+	authSubs, err := client.AuthenticationDataDocumentApi.QueryAuthSubsData(ctx, &queryAuthSubsDataRequest)
+	if err != nil {
+		return nil, err
 	}
 	if authSubs == nil || authSubs.Udr_DR_AuthenticationSubscription == nil {
-		problemDetails := openapi.ProblemDetailsSystemFailure("UDR returned an empty authentication subscription")
-		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
-		c.JSON(int(problemDetails.Status), problemDetails)
-		return
+		return nil, errors.New("UDR returned an empty authentication subscription")
+	}
+	return authSubs.Udr_DR_AuthenticationSubscription, nil
+}
+
+// decryptKeyMaterial decodes a hex key field of the authentication subscription. The field
+// holds plaintext when the subscription has no protection parameter ID or one configured as
+// plaintext, otherwise it holds ciphertext and is decrypted by the key provider before use.
+func (p *Processor) decryptKeyMaterial(
+	authSubscription *models.Udr_DR_AuthenticationSubscription,
+	value string,
+) ([]byte, error) {
+	raw, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	protectionParameterId := authSubscription.ProtectionParameterId
+	if protectionParameterId == "" || slices.Contains(p.Context().PlaintextKeyIds, protectionParameterId) {
+		return raw, nil
+	}
+	keyProvider := p.Context().KeyProvider
+	if keyProvider == nil {
+		return nil, fmt.Errorf("no key provider is configured for protection parameter [%s]",
+			authSubscription.ProtectionParameterId)
+	}
+	return keyProvider.Decrypt(authSubscription.ProtectionParameterId, raw)
+}
+
+// akaVector holds the outputs of one run of the AKA functions (TS 33.102 6.3.2)
+type akaVector struct {
+	rand     []byte
+	xres     []byte
+	autn     []byte
+	ck       []byte
+	ik       []byte
+	sqnXorAK []byte
+}
+
+// generateAkaVector issues a single vector, see generateAkaVectors.
+// It is shared by every UEAU procedure which issues one AKA based vector.
+func (p *Processor) generateAkaVector(
+	ctx context.Context,
+	client *Nudr_DataRepository.APIClient,
+	supi string,
+	authSubscription *models.Udr_DR_AuthenticationSubscription,
+	resyncInfo *models.Udm_UEAU_ResynchronizationInfo,
+) (*akaVector, *models.ProblemDetails) {
+	vectors, problemDetails := p.generateAkaVectors(ctx, client, supi, authSubscription, resyncInfo, 1)
+	if problemDetails != nil {
+		return nil, problemDetails
+	}
+	return vectors[0], nil
+}
+
+// generateAkaVectors runs the AKA algorithm of the authentication subscription count times,
+// handles the re-synchronization procedure and writes the next SQN back to the UDR.
+// The vectors use consecutive SQNs and the UDR is updated once for the whole batch.
+func (p *Processor) generateAkaVectors(
+	ctx context.Context,
+	client *Nudr_DataRepository.APIClient,
+	supi string,
+	authSubscription *models.Udr_DR_AuthenticationSubscription,
+	resyncInfo *models.Udm_UEAU_ResynchronizationInfo,
+	count int,
+) ([]*akaVector, *models.ProblemDetails) {
+	if p.Context().AuthLockedOut(supi, time.Now()) {
+		logger.UeauLog.Warnf("Authentication of supi[%s] is locked out", supi)
+		return nil, &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationLockedOut,
+			Detail: "authentication is rejected after repeated failures, retry later",
+		}
 	}
-	authSubscription := authSubs.Udr_DR_AuthenticationSubscription
 
 	/*
 		K, RAND, CK, IK: 128 bits (16 bytes) (hex len = 32)
@@ -182,290 +350,377 @@ func (p *Processor) GenerateAuthDataProcedure(
 		AMF: 16 bits (2 bytes) (hex len = 4) TS33.102 - Annex H
 	*/
 
-	hasOPC := false
-	var kStr, opcStr string
+	// the operator variant of the subscription depends on the algorithm set of the USIM
+	algorithmId := strings.ToLower(authSubscription.AlgorithmId)
+	// OP is operator-wide, it is only used when the subscription has no OPc
+	var opcName, opcStr, opName, opStr string
+	var opcLen int
+	switch algorithmId {
+	case aka.AlgorithmTuak:
+		opcName, opcStr, opcLen = "EncTopcKey", authSubscription.EncTopcKey, topcStrLen
+		opName, opStr = "TOP", p.Context().OperatorTop
+	case aka.AlgorithmXor:
+	case aka.AlgorithmMilenage, "":
+		opcName, opcStr, opcLen = "EncOpcKey", authSubscription.EncOpcKey, opcStrLen
+		opName, opStr = "OP", p.Context().OperatorOp
+	default:
+		logger.UeauLog.Errorf("Unsupported AlgorithmId [%s] of supi[%s]", authSubscription.AlgorithmId, supi)
+		return nil, &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationRejected,
+			Detail: "unsupported AlgorithmId [" + authSubscription.AlgorithmId + "]",
+		}
+	}
+
 	var k, op, opc []byte
+	var err error
 	if authSubscription.EncPermanentKey != "" {
-		kStr = authSubscription.EncPermanentKey
-		if len(kStr) == keyStrLen {
-			k, err = hex.DecodeString(kStr)
-			if err != nil {
-				logger.UeauLog.Errorln("err:", err)
+		k, err = p.decryptKeyMaterial(authSubscription, authSubscription.EncPermanentKey)
+		if err != nil {
+			logger.UeauLog.Errorf("EncPermanentKey of supi[%s]: %+v", supi, err)
+			return nil, &models.ProblemDetails{
+				Status: http.StatusForbidden,
+				Cause:  authenticationRejected,
+				Detail: "EncPermanentKey: " + err.Error(),
 			}
-		} else {
-			problemDetails := &models.ProblemDetails{
+		}
+		// TUAK also accepts 256 bits keys
+		kStrLen := 2 * len(k)
+		if kStrLen != keyStrLen && (algorithmId != aka.AlgorithmTuak || kStrLen != 2*keyStrLen) {
+			logger.UeauLog.Errorln("kStr length is ", kStrLen)
+			return nil, &models.ProblemDetails{
 				Status: http.StatusForbidden,
 				Cause:  authenticationRejected,
 				Detail: "len(kStr) != keyStrLen",
 			}
-
-			logger.UeauLog.Errorln("kStr length is ", len(kStr))
-			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
-			c.JSON(int(problemDetails.Status), problemDetails)
-			return
 		}
 	} else {
-		problemDetails := &models.ProblemDetails{
+		logger.UeauLog.Errorln("Nil PermanentKey")
+		return nil, &models.ProblemDetails{
 			Status: http.StatusForbidden,
 			Cause:  authenticationRejected,
 			Detail: "EncPermanentKey == ''",
 		}
-
-		logger.UeauLog.Errorln("Nil PermanentKey")
-		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
-		c.JSON(int(problemDetails.Status), problemDetails)
-		return
 	}
 
-	if authSubscription.EncOpcKey != "" {
-		opcStr = authSubscription.EncOpcKey
-		if len(opcStr) == opcStrLen {
-			opc, err = hex.DecodeString(opcStr)
-			if err != nil {
-				logger.UeauLog.Errorln("err:", err)
-			} else {
-				hasOPC = true
+	if opcStr != "" {
+		opc, err = p.decryptKeyMaterial(authSubscription, opcStr)
+		if err != nil {
+			logger.UeauLog.Errorf("%s of supi[%s]: %+v", opcName, supi, err)
+			return nil, &models.ProblemDetails{
+				Status: http.StatusForbidden,
+				Cause:  authenticationRejected,
+				Detail: opcName + ": " + err.Error(),
 			}
-		} else {
-			logger.UeauLog.Errorln("opcStr length is ", len(opcStr))
 		}
-	} else {
-		logger.UeauLog.Infoln("Nil Opc")
-	}
-
-	if !hasOPC {
-		problemDetails := &models.ProblemDetails{
-			Status: http.StatusForbidden,
-			Cause:  authenticationRejected,
+		if 2*len(opc) != opcLen {
+			logger.UeauLog.Errorf("%s length is %d", opcName, 2*len(opc))
+			return nil, &models.ProblemDetails{
+				Status: http.StatusForbidden,
+				Cause:  authenticationRejected,
+				Detail: fmt.Sprintf("len(%s) != %d", opcName, opcLen),
+			}
 		}
-		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
-		c.JSON(int(problemDetails.Status), problemDetails)
-		return
 	}
 
-	if authSubscription.SequenceNumber == nil {
-		problemDetails := &models.ProblemDetails{
-			Status: http.StatusForbidden,
-			Cause:  authenticationRejected,
-			Detail: "SequenceNumber is missing",
+	if opcStr == "" && opName != "" {
+		if opStr == "" {
+			detail := fmt.Sprintf("%s is not provisioned for the subscription and no operator %s is configured",
+				opcName, opName)
+			logger.UeauLog.Errorf("supi[%s]: %s", supi, detail)
+			return nil, &models.ProblemDetails{
+				Status: http.StatusForbidden,
+				Cause:  authenticationRejected,
+				Detail: detail,
+			}
 		}
-		logger.UeauLog.Errorln("Nil SequenceNumber")
-		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
-		c.JSON(int(problemDetails.Status), problemDetails)
-		return
-	}
-
-	sqnStr := p.strictHex(authSubscription.SequenceNumber.Sqn, 12)
-	logger.UeauLog.Traceln("sqnStr", sqnStr)
-	sqn, err := hex.DecodeString(sqnStr)
-	if err != nil {
-		problemDetails := &models.ProblemDetails{
-			Status: http.StatusForbidden,
-			Cause:  authenticationRejected,
-			Detail: err.Error(),
+		logger.UeauLog.Debugf("supi[%s]: derive %s from K and the operator %s", supi, opcName, opName)
+		op, err = hex.DecodeString(opStr)
+		if err != nil {
+			logger.UeauLog.Errorln("err:", err)
+			return nil, &models.ProblemDetails{
+				Status: http.StatusForbidden,
+				Cause:  authenticationRejected,
+				Detail: err.Error(),
+			}
 		}
-
-		logger.UeauLog.Errorln("err:", err)
-		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
-		c.JSON(int(problemDetails.Status), problemDetails)
-		return
 	}
 
-	logger.UeauLog.Tracef("K=[%x], sqn=[%x], OP=[%x], OPC=[%x]", k, sqn, op, opc)
-
-	RAND := make([]byte, 16)
-	_, err = cryptoRand.Read(RAND)
+	alg, err := aka.New(algorithmId, k, op, opc)
 	if err != nil {
-		problemDetails := &models.ProblemDetails{
+		logger.UeauLog.Errorf("AKA algorithm for supi[%s]: %+v", supi, err)
+		return nil, &models.ProblemDetails{
 			Status: http.StatusForbidden,
 			Cause:  authenticationRejected,
 			Detail: err.Error(),
 		}
-
-		logger.UeauLog.Errorln("err:", err)
-		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
-		c.JSON(int(problemDetails.Status), problemDetails)
-		return
 	}
+	logger.UeauLog.Tracef("AKA algorithm [%s] for supi[%s]", alg.Name(), supi)
+
+	logger.UeauLog.Tracef("K=[%x], OP=[%x], OPC=[%x]", k, op, opc)
 
 	amfStr := p.strictHex(authSubscription.AuthenticationManagementField, 4)
 	logger.UeauLog.Traceln("amfStr", amfStr)
 	AMF, err := hex.DecodeString(amfStr)
 	if err != nil {
-		problemDetails := &models.ProblemDetails{
+		logger.UeauLog.Errorln("err:", err)
+		return nil, &models.ProblemDetails{
 			Status: http.StatusForbidden,
 			Cause:  authenticationRejected,
 			Detail: err.Error(),
 		}
-
-		logger.UeauLog.Errorln("err:", err)
-		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
-		c.JSON(int(problemDetails.Status), problemDetails)
-		return
 	}
 
-	logger.UeauLog.Tracef("RAND=[%x], AMF=[%x]", RAND, AMF)
+	logger.UeauLog.Tracef("AMF=[%x]", AMF)
 
 	// re-synchronization
-	if authInfoRequest.ResynchronizationInfo != nil {
+	var sqnMS *uint64
+	if resyncInfo != nil {
 		logger.UeauLog.Infof("Authentication re-synchronization")
 
-		Auts, deCodeErr := hex.DecodeString(authInfoRequest.ResynchronizationInfo.Auts)
+		Auts, deCodeErr := hex.DecodeString(resyncInfo.Auts)
 		if deCodeErr != nil {
-			problemDetails := &models.ProblemDetails{
+			logger.UeauLog.Errorln("err:", deCodeErr)
+			return nil, &models.ProblemDetails{
 				Status: http.StatusForbidden,
 				Cause:  authenticationRejected,
 				Detail: deCodeErr.Error(),
 			}
-
-			logger.UeauLog.Errorln("err:", deCodeErr)
-			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
-			c.JSON(int(problemDetails.Status), problemDetails)
-			return
 		}
 
-		randHex, deCodeErr := hex.DecodeString(authInfoRequest.ResynchronizationInfo.Rand)
+		randHex, deCodeErr := hex.DecodeString(resyncInfo.Rand)
 		if deCodeErr != nil {
-			problemDetails := &models.ProblemDetails{
+			logger.UeauLog.Errorln("err:", deCodeErr)
+			return nil, &models.ProblemDetails{
 				Status: http.StatusForbidden,
 				Cause:  authenticationRejected,
 				Detail: deCodeErr.Error(),
 			}
-
-			logger.UeauLog.Errorln("err:", deCodeErr)
-			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
-			c.JSON(int(problemDetails.Status), problemDetails)
-			return
 		}
 
-		SQNms, macS, err := p.aucSQN(opc, k, Auts, randHex)
+		SQNms, macS, err := p.aucSQN(alg, Auts, randHex)
 		if err != nil {
 			logger.UeauLog.Errorln("aucSQN error:", err)
-			problemDetails := &models.ProblemDetails{
+			var macFailure *milenage.MACFailureError
+			if errors.As(err, &macFailure) {
+				p.recordResyncFailure(supi)
+			}
+			return nil, &models.ProblemDetails{
 				Status: http.StatusForbidden,
 				Cause:  authenticationRejected,
 				Detail: err.Error(),
 			}
-			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
-			c.JSON(int(problemDetails.Status), problemDetails)
-			return
 		}
 
 		if reflect.DeepEqual(macS, Auts[6:]) {
-			_, err = cryptoRand.Read(RAND)
-			if err != nil {
-				problemDetails := &models.ProblemDetails{
-					Status: http.StatusForbidden,
-					Cause:  authenticationRejected,
-					Detail: err.Error(),
-				}
-
-				logger.UeauLog.Errorln("err:", err)
-				c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
-				c.JSON(int(problemDetails.Status), problemDetails)
-				return
-			}
-
-			// increment sqn authSubs.SequenceNumber
-			bigSQN := big.NewInt(0)
-			sqnStr = hex.EncodeToString(SQNms)
-			logger.UeauLog.Tracef("SQNstr=[%s]", sqnStr)
-			bigSQN.SetString(sqnStr, 16)
-
-			bigInc := big.NewInt(ind + 1)
-
-			bigP := big.NewInt(SqnMAx)
-			bigSQN = bigInc.Add(bigSQN, bigInc)
-			bigSQN = bigSQN.Mod(bigSQN, bigP)
-			sqnStr = fmt.Sprintf("%x", bigSQN)
-			sqnStr = p.strictHex(sqnStr, 12)
+			logger.UeauLog.Tracef("SQNms=[%x]", SQNms)
+			sqnMSValue := sqn.FromBytes(SQNms)
+			sqnMS = &sqnMSValue
 		} else {
-			logger.UeauLog.Errorf("Re-Sync MAC failed for UE with identity supiOrSuci=[%s], resolvedSupi=[%s]", supiOrSuci, supi)
+			logger.UeauLog.Errorf("Re-Sync MAC failed for UE with identity supi=[%s]", supi)
+			p.recordResyncFailure(supi)
 			logger.UeauLog.Errorln("MACS ", macS)
 			logger.UeauLog.Errorln("Auts[6:] ", Auts[6:])
 			logger.UeauLog.Errorln("Sqn ", SQNms)
-			problemDetails := &models.ProblemDetails{
+			return nil, &models.ProblemDetails{
 				Status: http.StatusForbidden,
 				Cause:  "modification is rejected",
 			}
-			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
-			c.JSON(int(problemDetails.Status), problemDetails)
-			return
 		}
 	}
 
-	// increment sqn
-	bigSQN := big.NewInt(0)
-	sqn, err = hex.DecodeString(sqnStr)
-	if err != nil {
-		problemDetails := &models.ProblemDetails{
-			Status: http.StatusForbidden,
-			Cause:  authenticationRejected,
-			Detail: err.Error(),
+	// the SQN read from the UDR may be outdated by another UDM instance, the subscription
+	// is read again until the update goes through
+	var sqns []uint64
+	for attempt := 1; ; attempt++ {
+		var conflict bool
+		var problemDetails *models.ProblemDetails
+		sqns, conflict, problemDetails = p.updateSqn(ctx, client, supi, authSubscription, sqnMS, count)
+		if problemDetails != nil {
+			return nil, problemDetails
+		}
+		if !conflict {
+			break
+		}
+		if attempt == maxSqnUpdateAttempts {
+			logger.UeauLog.Errorf("SQN of supi[%s] still conflicts after %d attempts", supi, attempt)
+			return nil, &models.ProblemDetails{
+				Status: http.StatusInternalServerError,
+				Cause:  sqnUpdateConflict,
+				Detail: "the SQN was concurrently updated in the UDR",
+			}
+		}
+		logger.UeauLog.Warnf("SQN of supi[%s] was concurrently updated, read it again", supi)
+		if authSubscription, err = p.readAuthSubscription(ctx, client, supi); err != nil {
+			logger.UeauLog.Errorf("Error on QueryAuthSubsData: %+v", err)
+			return nil, openapi.ProblemDetailsSystemFailure(err.Error())
 		}
-
-		logger.UeauLog.Errorln("err:", err)
-		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
-		c.JSON(int(problemDetails.Status), problemDetails)
-		return
 	}
 
-	bigSQN.SetString(sqnStr, 16)
-
-	bigInc := big.NewInt(1)
-	bigSQN = bigInc.Add(bigSQN, bigInc)
+	vectors := make([]*akaVector, 0, count)
+	for _, vectorSqnValue := range sqns {
+		vectorSqn := sqn.Bytes(vectorSqnValue)
+		RAND := make([]byte, 16)
+		_, err = cryptoRand.Read(RAND)
+		if err != nil {
+			logger.UeauLog.Errorln("err:", err)
+			return nil, &models.ProblemDetails{
+				Status: http.StatusForbidden,
+				Cause:  authenticationRejected,
+				Detail: err.Error(),
+			}
+		}
+		logger.UeauLog.Tracef("RAND=[%x], SQN=[%x]", RAND, vectorSqn)
 
-	SQNheStr := fmt.Sprintf("%x", bigSQN)
-	SQNheStr = p.strictHex(SQNheStr, 12)
-	patchItemArray := []models.PatchItem{
-		{
-			Op:   models.PatchOperation_REPLACE,
-			Path: "/sequenceNumber",
-			Value: models.Udr_DR_SequenceNumber{
-				Sqn: SQNheStr,
-			},
-		},
+		// Run the AKA algorithm
+		IK, CK, RES, AUTN, err := alg.GenerateAKAParameters(RAND, vectorSqn, AMF)
+		if err != nil {
+			logger.UeauLog.Errorf("%s GenerateAKAParameters err: %+v", alg.Name(), err)
+			return nil, &models.ProblemDetails{
+				Status: http.StatusForbidden,
+				Cause:  authenticationRejected,
+				Detail: err.Error(),
+			}
+		}
+		logger.UeauLog.Tracef("%s RES=[%s]", alg.Name(), hex.EncodeToString(RES))
+		logger.UeauLog.Tracef("AUTN=[%x]", AUTN)
+
+		SQNxorAK := AUTN[0:6]
+		logger.UeauLog.Tracef("SQN xor AK=[%x]", SQNxorAK)
+
+		vectors = append(vectors, &akaVector{
+			rand:     RAND,
+			xres:     RES,
+			autn:     AUTN,
+			ck:       CK,
+			ik:       IK,
+			sqnXorAK: SQNxorAK,
+		})
 	}
+	return vectors, nil
+}
 
-	logger.ProcLog.Infoln("ModifyAuthenticationSubscriptionRequest: ", patchItemArray)
-
-	var modifyAuthenticationSubscriptionRequest Nudr_DataRepository.ModifyAuthenticationSubscriptionRequest
-	modifyAuthenticationSubscriptionRequest.UeId = &supi
-	modifyAuthenticationSubscriptionRequest.RequestBody = patchItemArray
-	_, err = client.AuthenticationSubscriptionDocumentApi.ModifyAuthenticationSubscription(
-		ctx, &modifyAuthenticationSubscriptionRequest)
+// updateSqn reserves the SQNs of count vectors: the batch starts at the SQN_HE of the
+// subscription, or where a re-synchronization with sqnMS restarts it, and the UDR keeps the
+// SQN following the last vector. conflict reports that the SQN changed in the UDR since the
+// subscription was read.
+func (p *Processor) updateSqn(
+	ctx context.Context,
+	client *Nudr_DataRepository.APIClient,
+	supi string,
+	authSubscription *models.Udr_DR_AuthenticationSubscription,
+	sqnMS *uint64,
+	count int,
+) (sqns []uint64, conflict bool, problemDetails *models.ProblemDetails) {
+	if authSubscription.SequenceNumber == nil {
+		logger.UeauLog.Errorln("Nil SequenceNumber")
+		return nil, false, &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationRejected,
+			Detail: "SequenceNumber is missing",
+		}
+	}
+
+	sqnStr := p.strictHex(authSubscription.SequenceNumber.Sqn, 12)
+	logger.UeauLog.Traceln("sqnStr", sqnStr)
+	sqnHE, err := sqn.Parse(sqnStr)
 	if err != nil {
-		problemDetails := &models.ProblemDetails{
+		logger.UeauLog.Errorln("err:", err)
+		return nil, false, &models.ProblemDetails{
 			Status: http.StatusForbidden,
-			Cause:  "modification is rejected ",
+			Cause:  authenticationRejected,
 			Detail: err.Error(),
 		}
+	}
 
-		logger.UeauLog.Errorln("update sqn error:", err)
-		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
-		c.JSON(int(problemDetails.Status), problemDetails)
-		return
+	scheme := p.sqnScheme()
+	if sqnMS != nil {
+		// keep SQN_HE if the USIM accepts it, restart after SQN_MS otherwise
+		sqnHE, err = scheme.Resync(sqnHE, *sqnMS)
+		if err != nil {
+			logger.UeauLog.Errorf("Re-Sync of supi=[%s] err: %+v", supi, err)
+			return nil, false, &models.ProblemDetails{
+				Status: http.StatusForbidden,
+				Cause:  authenticationRejected,
+				Detail: err.Error(),
+			}
+		}
 	}
 
-	// Run milenage
-	IK, CK, RES, AUTN, err := milenage.GenerateAKAParameters(opc, k, RAND, sqn, AMF)
+	sqns, nextSqn, err := scheme.Batch(sqnHE, count)
 	if err != nil {
-		logger.UeauLog.Errorln("milenage GenerateAKAParameters err:", err)
+		logger.UeauLog.Errorf("SQN of supi=[%s] err: %+v", supi, err)
+		return nil, false, &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationRejected,
+			Detail: err.Error(),
+		}
 	}
-	logger.UeauLog.Tracef("milenage RES=[%s]", hex.EncodeToString(RES))
-	logger.UeauLog.Tracef("AUTN=[%x]", AUTN)
 
-	SQNxorAK := AUTN[0:6]
-	logger.UeauLog.Tracef("SQN xor AK=[%x]", SQNxorAK)
+	SQNheStr := sqn.Format(nextSqn)
+	// the test operation makes the update conditional on the SQN read above, a concurrent
+	// update from another UDM instance makes the UDR reject the whole patch
+	patchItemArray := []models.PatchItem{
+		{
+			Op:    models.PatchOperation_TEST,
+			Path:  "/sequenceNumber/sqn",
+			Value: authSubscription.SequenceNumber.Sqn,
+		},
+		{
+			Op:   models.PatchOperation_REPLACE,
+			Path: "/sequenceNumber",
+			Value: models.Udr_DR_SequenceNumber{
+				Sqn: SQNheStr,
+			},
+		},
+	}
 
+	logger.ProcLog.Infoln("ModifyAuthenticationSubscriptionRequest: ", patchItemArray)
+
+	var modifyAuthenticationSubscriptionRequest Nudr_DataRepository.ModifyAuthenticationSubscriptionRequest
+	modifyAuthenticationSubscriptionRequest.UeId = &supi
+	modifyAuthenticationSubscriptionRequest.RequestBody = patchItemArray
+	_, err = client.AuthenticationSubscriptionDocumentApi.ModifyAuthenticationSubscription(
+		ctx, &modifyAuthenticationSubscriptionRequest)
+	if err != nil {
+		if isSqnTestFailure(err) {
+			return nil, true, nil
+		}
+		logger.UeauLog.Errorln("update sqn error:", err)
+		return nil, false, &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  "modification is rejected",
+			Detail: err.Error(),
+		}
+	}
+	return sqns, false, nil
+}
+
+// isSqnTestFailure tells whether the UDR rejected the SQN update because its test
+// operation failed, the SQN stored differs from the one read
+func isSqnTestFailure(err error) bool {
+	apiError, ok := err.(openapi.GenericOpenAPIError)
+	return ok && (apiError.ErrorStatus == http.StatusConflict || apiError.ErrorStatus == http.StatusPreconditionFailed)
+}
+
+// buildAuthenticationVector derives the home environment vector for the authentication method
+// of the subscription: 5G HE AKA (XRES*, KAUSF) or EAP-AKA' (CK', IK'), see TS 33.501 6.1.3
+func (p *Processor) buildAuthenticationVector(
+	authMethod models.Udr_DR_AuthMethod,
+	servingNetworkName string,
+	vector *akaVector,
+) (models.Udm_UEAU_AuthType, *models.Udm_UEAU_AuthenticationVector) {
+	RAND, RES, AUTN, CK, IK, SQNxorAK := vector.rand, vector.xres, vector.autn, vector.ck, vector.ik, vector.sqnXorAK
+
+	var authType models.Udm_UEAU_AuthType
 	var av models.Udm_UEAU_AuthenticationVector
-	if authSubscription.AuthenticationMethod == models.Udr_DR_AuthMethod_5_G_AKA {
-		response.AuthType = models.Udm_UEAU_AuthType_5_G_AKA
+	if authMethod == models.Udr_DR_AuthMethod_5_G_AKA {
+		authType = models.Udm_UEAU_AuthType_5_G_AKA
 
 		// derive XRES*
 		key := append(CK, IK...)
 		FC := ueauth.FC_FOR_RES_STAR_XRES_STAR_DERIVATION
-		P0 := []byte(authInfoRequest.ServingNetworkName)
+		P0 := []byte(servingNetworkName)
 		P1 := RAND
 		P2 := RES
 
@@ -479,7 +734,7 @@ func (p *Processor) GenerateAuthDataProcedure(
 
 		// derive Kausf
 		FC = ueauth.FC_FOR_KAUSF_DERIVATION
-		P0 = []byte(authInfoRequest.ServingNetworkName)
+		P0 = []byte(servingNetworkName)
 		P1 = SQNxorAK
 		kdfValForKausf, err := ueauth.GetKDFValue(key, FC, P0, ueauth.KDFLen(P0), P1, ueauth.KDFLen(P1))
 		if err != nil {
@@ -494,11 +749,11 @@ func (p *Processor) GenerateAuthDataProcedure(
 		av.Kausf = hex.EncodeToString(kdfValForKausf)
 		av.AvType = models.Udm_UEAU_AvType_5_G_HE_AKA
 	} else { // EAP-AKA'
-		response.AuthType = models.Udm_UEAU_AuthType_EAP_AKA_PRIME
+		authType = models.Udm_UEAU_AuthType_EAP_AKA_PRIME
 		// derive CK' and IK'
 		key := append(CK, IK...)
 		FC := ueauth.FC_FOR_CK_PRIME_IK_PRIME_DERIVATION
-		P0 := []byte(authInfoRequest.ServingNetworkName)
+		P0 := []byte(servingNetworkName)
 		P1 := SQNxorAK
 		kdfVal, err := ueauth.GetKDFValue(key, FC, P0, ueauth.KDFLen(P0), P1, ueauth.KDFLen(P1))
 		if err != nil {
@@ -523,7 +778,497 @@ func (p *Processor) GenerateAuthDataProcedure(
 		av.AvType = models.Udm_UEAU_AvType_EAP_AKA_PRIME
 	}
 
-	response.AuthenticationVector = &av
+	return authType, &av
+}
+
+// authorizeServingNetwork checks that the serving network name is well formed and, when a
+// serving network policy is configured, that the subscriber may be served by that network:
+// a visited network needs a roaming agreement and the AM data must not bar the subscriber
+// from roaming there.
+func (p *Processor) authorizeServingNetwork(
+	ctx context.Context,
+	client *Nudr_DataRepository.APIClient,
+	supi string,
+	servingNetworkName string,
+) *models.ProblemDetails {
+	notAuthorized := func(detail string) *models.ProblemDetails {
+		logger.UeauLog.Warnf("Serving network [%s] is not authorized for supi[%s]: %s",
+			servingNetworkName, supi, detail)
+		return &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  servingNetworkNotAuthorized,
+			Detail: detail,
+		}
+	}
+
+	name, err := servingnetwork.Parse(servingNetworkName)
+	if err != nil {
+		return notAuthorized(err.Error())
+	}
+	policy := p.Context().ServingNetworkPolicy
+	if policy == nil || policy.IsHome(name) {
+		return nil
+	}
+	agreement, ok := policy.RoamingAgreement(name)
+	if !ok {
+		return notAuthorized("no roaming agreement with serving network " + servingNetworkName)
+	}
+
+	var queryAmDataRequest Nudr_DataRepository.QueryAmDataRequest
+	queryAmDataRequest.UeId = &supi
+	servingPlmnId := agreement.Mcc + agreement.Mnc
+	queryAmDataRequest.ServingPlmnId = &servingPlmnId
+	amDataResp, err := client.AccessAndMobilitySubscriptionDataDocumentApi.QueryAmData(ctx, &queryAmDataRequest)
+	if err != nil {
+		apiError, ok := err.(openapi.GenericOpenAPIError)
+		if ok && apiError.ErrorStatus == http.StatusNotFound {
+			// no AM data, hence no roaming restriction
+			return nil
+		}
+		logger.UeauLog.Errorf("Error on QueryAmData: %+v", err)
+		return openapi.ProblemDetailsSystemFailure(err.Error())
+	}
+	if amDataResp == nil || amDataResp.Udm_SDM_AccessAndMobilitySubscriptionData == nil {
+		return nil
+	}
+	// accessAllowed is mandatory in RoamingRestrictions (TS 29.503), a restriction omitting
+	// it reads as false and denies roaming on purpose: the UDM does not guess in favor of access
+	restrictions := amDataResp.Udm_SDM_AccessAndMobilitySubscriptionData.RoamingRestrictions
+	if restrictions != nil && !restrictions.AccessAllowed {
+		return notAuthorized("roaming is not allowed for the subscriber in serving network " + servingNetworkName)
+	}
+	return nil
+}
+
+func (p *Processor) GenerateAuthDataProcedure(
+	c *gin.Context,
+	authInfoRequest models.Udm_UEAU_AuthenticationInfoRequest,
+	supiOrSuci string,
+) {
+	ctx, pd, err := p.Context().GetTokenCtx(models.Nrf_NFMgmt_ServiceName_NUDR_DR, models.Nrf_NFMgmt_NFType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+	logger.UeauLog.Traceln("In GenerateAuthDataProcedure")
+
+	response := &models.Udm_UEAU_AuthenticationInfoResult{}
+	rand.New(rand.NewSource(time.Now().UnixNano()))
+	if p.routeSuci(c, supiOrSuci) {
+		return
+	}
+	supi, err := p.Context().SuciProfiles.ToSupi(supiOrSuci)
+	if err != nil {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationRejected,
+			Detail: err.Error(),
+		}
+
+		logger.UeauLog.Errorln("suciToSupi error: ", err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	logger.UeauLog.Tracef("supi conversion => [%s]", supi)
+
+	client, err := p.Consumer().CreateUDMClientToUDR(supi)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+	problemDetails := p.authorizeServingNetwork(ctx, client, supi, authInfoRequest.ServingNetworkName)
+	if problemDetails != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	// the SQN is read and written back under the per-SUPI lock
+	unlock := p.Context().LockSqn(supi)
+	defer unlock()
+
+	authSubscription := p.queryAuthSubscription(c, ctx, client, supi)
+	if authSubscription == nil {
+		return
+	}
+
+	vector, problemDetails := p.generateAkaVector(
+		ctx, client, supi, authSubscription, authInfoRequest.ResynchronizationInfo)
+	if problemDetails != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	response.AuthType, response.AuthenticationVector = p.buildAuthenticationVector(
+		authSubscription.AuthenticationMethod, authInfoRequest.ServingNetworkName, vector)
 	response.Supi = supi
 	c.JSON(http.StatusOK, response)
 }
+
+func (p *Processor) GenerateGbaAvProcedure(
+	c *gin.Context,
+	gbaAuthInfoRequest models.Udm_UEAU_GbaAuthenticationInfoRequest,
+	supi string,
+) {
+	ctx, pd, err := p.Context().GetTokenCtx(models.Nrf_NFMgmt_ServiceName_NUDR_DR, models.Nrf_NFMgmt_NFType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+	logger.UeauLog.Traceln("In GenerateGbaAvProcedure")
+
+	client, err := p.Consumer().CreateUDMClientToUDR(supi)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	// the SQN is read and written back under the per-SUPI lock
+	unlock := p.Context().LockSqn(supi)
+	defer unlock()
+
+	authSubscription := p.queryAuthSubscription(c, ctx, client, supi)
+	if authSubscription == nil {
+		return
+	}
+
+	var resyncInfo *models.Udm_UEAU_ResynchronizationInfo
+	if gbaAuthInfoRequest.ResynchronizationInfo != nil {
+		resyncInfo = &models.Udm_UEAU_ResynchronizationInfo{
+			Rand: gbaAuthInfoRequest.ResynchronizationInfo.Rand,
+			Auts: gbaAuthInfoRequest.ResynchronizationInfo.Auts,
+		}
+	}
+
+	vector, problemDetails := p.generateAkaVector(ctx, client, supi, authSubscription, resyncInfo)
+	if problemDetails != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	// TS 33.220 4.5.2: the BSF is provisioned with a plain 3G AKA vector
+	response := &models.Udm_UEAU_GbaAuthenticationInfoResult{
+		Var3gAkaAv: &models.Hss_imsUEAU_3GAkaAv{
+			Rand: hex.EncodeToString(vector.rand),
+			Xres: hex.EncodeToString(vector.xres),
+			Autn: hex.EncodeToString(vector.autn),
+			Ck:   hex.EncodeToString(vector.ck),
+			Ik:   hex.EncodeToString(vector.ik),
+		},
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// MaxNumOfRequestedVectors bounds the numOfRequestedVectors of an HssAuthenticationInfoRequest
+const MaxNumOfRequestedVectors = 5
+
+// fcForKasmeDerivation is the FC of the KASME derivation, TS 33.401 A.2
+const fcForKasmeDerivation = "10"
+
+// hssAuthenticationInfoResult is sent in place of models.Udm_UEAU_HssAuthenticationInfoResult,
+// whose generated HssAuthenticationVectors oneOf does not carry any vector field.
+// HssAuthenticationVectors holds either []models.Udm_UEAU_AvEpsAka or
+// []models.Udm_UEAU_AvImsGbaEapAka.
+type hssAuthenticationInfoResult struct {
+	SupportedFeatures        string      `json:"supportedFeatures,omitempty"`
+	HssAuthenticationVectors interface{} `json:"hssAuthenticationVectors"`
+}
+
+// servingNetworkIdentity encodes the PLMN identity as the SN id parameter of the KASME
+// derivation, i.e. the octets of the PLMN identity IE of TS 24.301
+func servingNetworkIdentity(plmnId *models.PlmnId) ([]byte, error) {
+	if plmnId == nil {
+		return nil, fmt.Errorf("servingNetworkId is missing")
+	}
+	mcc, mnc := plmnId.Mcc, plmnId.Mnc
+	if len(mcc) != 3 || (len(mnc) != 2 && len(mnc) != 3) {
+		return nil, fmt.Errorf("invalid servingNetworkId [%s-%s]", mcc, mnc)
+	}
+	if len(mnc) == 2 {
+		mnc += "f"
+	}
+	// MCC digit 2 | MCC digit 1, MNC digit 3 | MCC digit 3, MNC digit 2 | MNC digit 1
+	snId, err := hex.DecodeString(string([]byte{mcc[1], mcc[0], mnc[2], mcc[2], mnc[1], mnc[0]}))
+	if err != nil {
+		return nil, fmt.Errorf("invalid servingNetworkId [%s-%s]: %w", plmnId.Mcc, plmnId.Mnc, err)
+	}
+	return snId, nil
+}
+
+// deriveKasme derives KASME from CK, IK and SQN xor AK, bound to the serving network, TS 33.401 A.2
+func deriveKasme(vector *akaVector, snId []byte) ([]byte, error) {
+	key := append(append([]byte{}, vector.ck...), vector.ik...)
+	return ueauth.GetKDFValue(key, fcForKasmeDerivation,
+		snId, ueauth.KDFLen(snId), vector.sqnXorAK, ueauth.KDFLen(vector.sqnXorAK))
+}
+
+// GenerateAvProcedure issues the numOfRequestedVectors vectors requested by the HSS
+// (TS 29.503 5.4.2.6): EPS AKA vectors with KASME bound to the serving PLMN, and
+// quintets for IMS AKA, UMTS AKA, EAP-AKA and GBA
+func (p *Processor) GenerateAvProcedure(
+	c *gin.Context,
+	hssAuthInfoRequest models.Udm_UEAU_HssAuthenticationInfoRequest,
+	supi string,
+) {
+	ctx, pd, err := p.Context().GetTokenCtx(models.Nrf_NFMgmt_ServiceName_NUDR_DR, models.Nrf_NFMgmt_NFType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+	logger.UeauLog.Traceln("In GenerateAvProcedure")
+
+	client, err := p.Consumer().CreateUDMClientToUDR(supi)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	// the SQN is read and written back under the per-SUPI lock
+	unlock := p.Context().LockSqn(supi)
+	defer unlock()
+
+	var snId []byte
+	if hssAuthInfoRequest.HssAuthType == models.Udm_UEAU_HssAuthType_EPS_AKA {
+		snId, err = servingNetworkIdentity(hssAuthInfoRequest.ServingNetworkId)
+		if err != nil {
+			problemDetails := &models.ProblemDetails{
+				Status: http.StatusBadRequest,
+				Cause:  "MANDATORY_IE_INCORRECT",
+				Detail: err.Error(),
+			}
+			logger.UeauLog.Errorln("servingNetworkId error:", err)
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+			c.JSON(int(problemDetails.Status), problemDetails)
+			return
+		}
+	}
+
+	authSubscription := p.queryAuthSubscription(c, ctx, client, supi)
+	if authSubscription == nil {
+		return
+	}
+
+	vectors, problemDetails := p.generateAkaVectors(ctx, client, supi, authSubscription,
+		hssAuthInfoRequest.ResynchronizationInfo, int(hssAuthInfoRequest.NumOfRequestedVectors))
+	if problemDetails != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	avType := models.Udm_UEAU_HssAvType(hssAuthInfoRequest.HssAuthType)
+	response := &hssAuthenticationInfoResult{}
+	if avType == models.Udm_UEAU_HssAvType_EPS_AKA {
+		epsAkaVectors := make([]models.Udm_UEAU_AvEpsAka, 0, len(vectors))
+		for _, vector := range vectors {
+			kasme, err := deriveKasme(vector, snId)
+			if err != nil {
+				logger.UeauLog.Errorf("Get KASME err: %+v", err)
+				problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+				c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+				c.JSON(int(problemDetails.Status), problemDetails)
+				return
+			}
+			logger.UeauLog.Tracef("KASME=[%x]", kasme)
+
+			epsAkaVectors = append(epsAkaVectors, models.Udm_UEAU_AvEpsAka{
+				AvType: avType,
+				Rand:   hex.EncodeToString(vector.rand),
+				Xres:   hex.EncodeToString(vector.xres),
+				Autn:   hex.EncodeToString(vector.autn),
+				Kasme:  hex.EncodeToString(kasme),
+			})
+		}
+		response.HssAuthenticationVectors = epsAkaVectors
+	} else {
+		quintets := make([]models.Udm_UEAU_AvImsGbaEapAka, 0, len(vectors))
+		for _, vector := range vectors {
+			quintets = append(quintets, models.Udm_UEAU_AvImsGbaEapAka{
+				AvType: avType,
+				Rand:   hex.EncodeToString(vector.rand),
+				Xres:   hex.EncodeToString(vector.xres),
+				Autn:   hex.EncodeToString(vector.autn),
+				Ck:     hex.EncodeToString(vector.ck),
+				Ik:     hex.EncodeToString(vector.ik),
+			})
+		}
+		response.HssAuthenticationVectors = quintets
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// proSeAuthenticationInfoResult is sent in place of models.Udm_UEAU_ProSeAuthenticationInfoResult,
+// whose generated ProSeAuthenticationVectors oneOf does not carry any vector field
+type proSeAuthenticationInfoResult struct {
+	AuthType                   models.Udm_UEAU_AuthType               `json:"authType"`
+	ProseAuthenticationVectors []models.Udm_UEAU_AuthenticationVector `json:"proseAuthenticationVectors"`
+	Supi                       string                                 `json:"supi,omitempty"`
+	SupportedFeatures          string                                 `json:"supportedFeatures,omitempty"`
+}
+
+func (p *Processor) GenerateProseAVProcedure(
+	c *gin.Context,
+	proseAuthInfoRequest models.Udm_UEAU_ProSeAuthenticationInfoRequest,
+	supiOrSuci string,
+) {
+	ctx, pd, err := p.Context().GetTokenCtx(models.Nrf_NFMgmt_ServiceName_NUDR_DR, models.Nrf_NFMgmt_NFType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+	logger.UeauLog.Traceln("In GenerateProseAVProcedure")
+
+	if p.routeSuci(c, supiOrSuci) {
+		return
+	}
+	supi, err := p.Context().SuciProfiles.ToSupi(supiOrSuci)
+	if err != nil {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationRejected,
+			Detail: err.Error(),
+		}
+
+		logger.UeauLog.Errorln("suciToSupi error: ", err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	logger.UeauLog.Tracef("supi conversion => [%s]", supi)
+
+	client, err := p.Consumer().CreateUDMClientToUDR(supi)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+	problemDetails := p.authorizeServingNetwork(ctx, client, supi, proseAuthInfoRequest.ServingNetworkName)
+	if problemDetails != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	// the SQN is read and written back under the per-SUPI lock
+	unlock := p.Context().LockSqn(supi)
+	defer unlock()
+
+	authSubscription := p.queryAuthSubscription(c, ctx, client, supi)
+	if authSubscription == nil {
+		return
+	}
+
+	vector, problemDetails := p.generateAkaVector(
+		ctx, client, supi, authSubscription, proseAuthInfoRequest.ResynchronizationInfo)
+	if problemDetails != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	// TS 33.503 6.3.3.3: the ProSe AV is derived like the primary authentication vector,
+	// bound to the serving network name received from the 5G PKMF
+	authType, av := p.buildAuthenticationVector(
+		authSubscription.AuthenticationMethod, proseAuthInfoRequest.ServingNetworkName, vector)
+
+	response := &proSeAuthenticationInfoResult{
+		AuthType:                   authType,
+		ProseAuthenticationVectors: []models.Udm_UEAU_AuthenticationVector{*av},
+		Supi:                       supi,
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetRgAuthDataProcedure tells the AUSF whether the authentication of a 5G-RG/FN-RG performed
+// by the wireline access network is accepted (TS 33.501 7B.7). An RG provisioned with 5G
+// credentials is always authenticated by the 5GC, while an RG without credentials relies on
+// the authentication indicated by the W-AGF.
+func (p *Processor) GetRgAuthDataProcedure(
+	c *gin.Context,
+	supiOrSuci string,
+	authenticatedInd bool,
+	supportedFeatures string,
+) {
+	ctx, pd, err := p.Context().GetTokenCtx(models.Nrf_NFMgmt_ServiceName_NUDR_DR, models.Nrf_NFMgmt_NFType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+	logger.UeauLog.Traceln("In GetRgAuthDataProcedure")
+
+	if p.routeSuci(c, supiOrSuci) {
+		return
+	}
+	supi, err := p.Context().SuciProfiles.ToSupi(supiOrSuci)
+	if err != nil {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationRejected,
+			Detail: err.Error(),
+		}
+
+		logger.UeauLog.Errorln("suciToSupi error: ", err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	logger.UeauLog.Tracef("supi conversion => [%s]", supi)
+
+	client, err := p.Consumer().CreateUDMClientToUDR(supi)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	authSubscription := p.queryAuthSubscription(c, ctx, client, supi)
+	if authSubscription == nil {
+		return
+	}
+
+	rgAuthCtx := &models.Udm_UEAU_RgAuthCtx{
+		Supi:              supi,
+		SupportedFeatures: supportedFeatures,
+	}
+	has5gCredentials := authSubscription.AuthenticationMethod != "" && authSubscription.EncPermanentKey != ""
+	switch {
+	case has5gCredentials:
+		rgAuthCtx.AuthInd = false
+	case authenticatedInd:
+		rgAuthCtx.AuthInd = true
+	default:
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  authenticationRejected,
+			Detail: "RG has no 5G credentials and is not authenticated by the access network",
+		}
+
+		logger.UeauLog.Warnf("RG [%s] can not be authenticated", supi)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	c.JSON(http.StatusOK, rgAuthCtx)
+}
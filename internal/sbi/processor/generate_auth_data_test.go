@@ -1,10 +1,17 @@
 package processor
 
 import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
 	"encoding/json"
 	"io"
+	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/h2non/gock"
@@ -15,9 +22,35 @@ import (
 	"github.com/free5gc/openapi/models"
 	udm_context "github.com/free5gc/udm/internal/context"
 	"github.com/free5gc/udm/internal/sbi/consumer"
+	"github.com/free5gc/udm/pkg/keyprovider"
 	mockapp "github.com/free5gc/udm/pkg/mockapp"
+	"github.com/free5gc/udm/pkg/servingnetwork"
+	"github.com/free5gc/util/milenage"
+	"github.com/free5gc/util/ueauth"
 )
 
+// newTestProcessor returns a processor whose app serves udmContext, with the UE of supi
+// fetching its data from the UDR that gock mocks
+func newTestProcessor(t *testing.T, udmContext *udm_context.UDMContext, supi string) *Processor {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	mockApp := mockapp.NewMockApp(ctrl)
+	testConsumer, err := consumer.NewConsumer(mockApp)
+	require.NoError(t, err)
+	testProcessor, err := NewProcessor(mockApp)
+	require.NoError(t, err)
+
+	ue := udm_context.GetSelf().NewUdmUe(supi)
+	ue.UdrUri = "http://127.0.0.4:8000"
+	t.Cleanup(func() {
+		udm_context.GetSelf().UdmUePool.Delete(supi)
+	})
+
+	mockApp.EXPECT().Consumer().Return(testConsumer).AnyTimes()
+	mockApp.EXPECT().Context().Return(udmContext).AnyTimes()
+	return testProcessor
+}
+
 func TestGenerateAuthDataProcedure(t *testing.T) {
 	defer gock.Off() // Flush pending mocks after test execution
 
@@ -26,10 +59,10 @@ func TestGenerateAuthDataProcedure(t *testing.T) {
 	queryRes := models.Udr_DR_AuthenticationSubscription{
 		AuthenticationMethod:          models.Udr_DR_AuthMethod_5_G_AKA,
 		EncPermanentKey:               "8baf473f2f8fd09487cccbd7097c6862",
-		ProtectionParameterId:         "8baf473f2f8fd09487cccbd7097c6862",
+		ProtectionParameterId:         "",
 		SequenceNumber:                &models.Udr_DR_SequenceNumber{Sqn: "000000000023"},
 		AuthenticationManagementField: "8000",
-		AlgorithmId:                   "128-EEA0",
+		AlgorithmId:                   "milenage",
 		EncOpcKey:                     "8e27b6af0e692e750f32667a3b14605d",
 		EncTopcKey:                    "8e27b6",
 	}
@@ -69,7 +102,7 @@ func TestGenerateAuthDataProcedure(t *testing.T) {
 	).AnyTimes()
 
 	authInfoReq := models.Udm_UEAU_AuthenticationInfoRequest{
-		ServingNetworkName: "internet",
+		ServingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org",
 	}
 	httpRecorder := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(httpRecorder)
@@ -147,7 +180,7 @@ func TestGenerateAuthDataProcedure_UDRError(t *testing.T) {
 	).AnyTimes()
 
 	authInfoReq := models.Udm_UEAU_AuthenticationInfoRequest{
-		ServingNetworkName: "internet",
+		ServingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org",
 	}
 	httpRecorder := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(httpRecorder)
@@ -170,3 +203,1153 @@ func TestGenerateAuthDataProcedure_UDRError(t *testing.T) {
 	require.True(t, json.Valid(rawBytes), "response body should be valid JSON, got: %s", body)
 	require.Contains(t, body, "USER_NOT_FOUND")
 }
+
+func TestGenerateAuthDataProcedure_ConcurrentSqn(t *testing.T) {
+	defer gock.Off()
+	openapi.InterceptInnerHttp2Client(t, false)
+
+	supi := "imsi-208930000000002"
+	k := "8baf473f2f8fd09487cccbd7097c6862"
+	opc := "8e27b6af0e692e750f32667a3b14605d"
+
+	// the UDR mock keeps the SQN and applies the conditional patch like a JSON patch document
+	var udrMutex sync.Mutex
+	storedSqn := "000000000023"
+
+	gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+		Get("/subscription-data/"+supi+"/authentication-data/authentication-subscription").
+		Persist().
+		Reply(200).
+		// let the concurrent requests overlap
+		Delay(5*time.Millisecond).
+		AddHeader("Content-Type", "application/json").
+		Map(func(res *http.Response) *http.Response {
+			udrMutex.Lock()
+			defer udrMutex.Unlock()
+			body, err := json.Marshal(models.Udr_DR_AuthenticationSubscription{
+				AuthenticationMethod:          models.Udr_DR_AuthMethod_5_G_AKA,
+				EncPermanentKey:               k,
+				SequenceNumber:                &models.Udr_DR_SequenceNumber{Sqn: storedSqn},
+				AuthenticationManagementField: "8000",
+				EncOpcKey:                     opc,
+			})
+			require.NoError(t, err)
+			res.Body = io.NopCloser(bytes.NewReader(body))
+			return res
+		})
+
+	gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+		Patch("/subscription-data/" + supi + "/authentication-data/authentication-subscription").
+		Persist().
+		AddMatcher(func(req *http.Request, _ *gock.Request) (bool, error) {
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				return false, err
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			var patchItems []struct {
+				Op    models.PatchOperation `json:"op"`
+				Path  string                `json:"path"`
+				Value json.RawMessage       `json:"value"`
+			}
+			if err = json.Unmarshal(body, &patchItems); err != nil {
+				return false, err
+			}
+
+			udrMutex.Lock()
+			defer udrMutex.Unlock()
+			next := storedSqn
+			for _, item := range patchItems {
+				switch item.Op {
+				case models.PatchOperation_TEST:
+					if string(item.Value) != `"`+storedSqn+`"` {
+						// left to the conflict mock below
+						return false, nil
+					}
+				case models.PatchOperation_REPLACE:
+					var seq models.Udr_DR_SequenceNumber
+					if err = json.Unmarshal(item.Value, &seq); err != nil {
+						return false, err
+					}
+					next = seq.Sqn
+				}
+			}
+			storedSqn = next
+			return true, nil
+		}).
+		Reply(204)
+
+	gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+		Patch("/subscription-data/"+supi+"/authentication-data/authentication-subscription").
+		Persist().
+		Reply(409).
+		AddHeader("Content-Type", "application/problem+json").
+		JSON(models.ProblemDetails{Status: http.StatusConflict})
+
+	testProcessor := newTestProcessor(t, &udm_context.UDMContext{
+		NrfUri: "http://127.0.0.10:8000",
+		NfId:   "1",
+	}, supi)
+
+	const requests = 16
+	results := make([]*httptest.ResponseRecorder, requests)
+	var wg sync.WaitGroup
+	for i := range results {
+		results[i] = httptest.NewRecorder()
+		wg.Add(1)
+		go func(httpRecorder *httptest.ResponseRecorder) {
+			defer wg.Done()
+			c, _ := gin.CreateTestContext(httpRecorder)
+			authInfoReq := models.Udm_UEAU_AuthenticationInfoRequest{
+				ServingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org",
+			}
+			testProcessor.GenerateAuthDataProcedure(c, authInfoReq, supi)
+		}(results[i])
+	}
+	wg.Wait()
+
+	kBytes, err := hex.DecodeString(k)
+	require.NoError(t, err)
+	opcBytes, err := hex.DecodeString(opc)
+	require.NoError(t, err)
+
+	// every AUTN carries its own SQN
+	sqns := make(map[string]bool)
+	for _, httpRecorder := range results {
+		require.Equal(t, 200, httpRecorder.Code, httpRecorder.Body.String())
+		var res models.Udm_UEAU_AuthenticationInfoResult
+		require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &res))
+
+		randBytes, err := hex.DecodeString(res.AuthenticationVector.Rand)
+		require.NoError(t, err)
+		autnBytes, err := hex.DecodeString(res.AuthenticationVector.Autn)
+		require.NoError(t, err)
+		sqnHE, _, _, _, _, err := milenage.GenerateKeysWithAUTN(opcBytes, kBytes, randBytes, autnBytes)
+		require.NoError(t, err)
+
+		sqnStr := hex.EncodeToString(sqnHE)
+		require.False(t, sqns[sqnStr], "SQN %s is issued twice", sqnStr)
+		sqns[sqnStr] = true
+	}
+	require.Len(t, sqns, requests)
+}
+
+func TestGenerateAuthDataProcedure_SqnUpdateConflict(t *testing.T) {
+	const supi = "imsi-208930000000002"
+	authSubscription := func(sqn string) models.Udr_DR_AuthenticationSubscription {
+		return models.Udr_DR_AuthenticationSubscription{
+			AuthenticationMethod:          models.Udr_DR_AuthMethod_5_G_AKA,
+			EncPermanentKey:               "8baf473f2f8fd09487cccbd7097c6862",
+			SequenceNumber:                &models.Udr_DR_SequenceNumber{Sqn: sqn},
+			AuthenticationManagementField: "8000",
+			EncOpcKey:                     "8e27b6af0e692e750f32667a3b14605d",
+		}
+	}
+	patchWith := func(sqn string) func(*http.Request, *gock.Request) (bool, error) {
+		return func(req *http.Request, _ *gock.Request) (bool, error) {
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				return false, err
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			return strings.Contains(string(body), `"sqn":"`+sqn+`"`), nil
+		}
+	}
+	generateAuthData := func() *httptest.ResponseRecorder {
+		testProcessor := newTestProcessor(t, &udm_context.UDMContext{
+			NrfUri: "http://127.0.0.10:8000",
+			NfId:   "1",
+		}, supi)
+		httpRecorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(httpRecorder)
+		testProcessor.GenerateAuthDataProcedure(c, models.Udm_UEAU_AuthenticationInfoRequest{
+			ServingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org",
+		}, supi)
+		return httpRecorder
+	}
+
+	t.Run("SQN read again", func(t *testing.T) {
+		defer gock.Off()
+		openapi.InterceptInnerHttp2Client(t, false)
+
+		// another UDM instance moves the SQN from 23 to 40 between the read and the update
+		gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+			Get("/subscription-data/" + supi + "/authentication-data/authentication-subscription").
+			Reply(200).
+			JSON(authSubscription("000000000023"))
+		gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+			Patch("/subscription-data/" + supi + "/authentication-data/authentication-subscription").
+			Reply(409)
+		gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+			Get("/subscription-data/" + supi + "/authentication-data/authentication-subscription").
+			Reply(200).
+			JSON(authSubscription("000000000040"))
+		gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+			Patch("/subscription-data/" + supi + "/authentication-data/authentication-subscription").
+			AddMatcher(patchWith("000000000041")).
+			Reply(204)
+
+		httpRecorder := generateAuthData()
+		require.Equal(t, http.StatusOK, httpRecorder.Code, httpRecorder.Body.String())
+		require.True(t, gock.IsDone())
+	})
+
+	t.Run("conflict persists", func(t *testing.T) {
+		defer gock.Off()
+		openapi.InterceptInnerHttp2Client(t, false)
+
+		gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+			Get("/subscription-data/" + supi + "/authentication-data/authentication-subscription").
+			Times(maxSqnUpdateAttempts).
+			Reply(200).
+			JSON(authSubscription("000000000023"))
+		gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+			Patch("/subscription-data/" + supi + "/authentication-data/authentication-subscription").
+			Times(maxSqnUpdateAttempts).
+			Reply(409)
+
+		httpRecorder := generateAuthData()
+		require.Equal(t, http.StatusInternalServerError, httpRecorder.Code)
+		require.True(t, gock.IsDone())
+		var problemDetails models.ProblemDetails
+		require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &problemDetails))
+		require.Equal(t, "SQN_UPDATE_CONFLICT", problemDetails.Cause)
+	})
+}
+
+func TestAuthFailureLockout(t *testing.T) {
+	defer gock.Off()
+	openapi.InterceptInnerHttp2Client(t, false)
+
+	supi := "imsi-208930000000003"
+	gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+		Get("/subscription-data/"+supi+"/authentication-data/authentication-subscription").
+		Persist().
+		Reply(200).
+		AddHeader("Content-Type", "application/json").
+		JSON(models.Udr_DR_AuthenticationSubscription{
+			AuthenticationMethod:          models.Udr_DR_AuthMethod_5_G_AKA,
+			EncPermanentKey:               "8baf473f2f8fd09487cccbd7097c6862",
+			SequenceNumber:                &models.Udr_DR_SequenceNumber{Sqn: "000000000023"},
+			AuthenticationManagementField: "8000",
+			EncOpcKey:                     "8e27b6af0e692e750f32667a3b14605d",
+		})
+	gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+		Put("/subscription-data/" + supi + "/authentication-data/authentication-status").
+		Persist().
+		Reply(204)
+
+	udmContext := &udm_context.UDMContext{
+		NrfUri: "http://127.0.0.10:8000",
+		NfId:   "1",
+		AuthFailurePolicy: &udm_context.AuthFailurePolicy{
+			MaxResyncFailures:       2,
+			MaxConfirmationFailures: 3,
+			LockoutDuration:         time.Minute,
+		},
+	}
+	testProcessor := newTestProcessor(t, udmContext, supi)
+
+	generateAuthData := func(resyncInfo *models.Udm_UEAU_ResynchronizationInfo) models.ProblemDetails {
+		httpRecorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(httpRecorder)
+		testProcessor.GenerateAuthDataProcedure(c, models.Udm_UEAU_AuthenticationInfoRequest{
+			ServingNetworkName:    "5G:mnc093.mcc208.3gppnetwork.org",
+			ResynchronizationInfo: resyncInfo,
+		}, supi)
+		require.Equal(t, http.StatusForbidden, httpRecorder.Code)
+		var problemDetails models.ProblemDetails
+		require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &problemDetails))
+		return problemDetails
+	}
+	authFailure := func() authFailureStatus {
+		httpRecorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(httpRecorder)
+		testProcessor.GetAuthFailureProcedure(c, supi)
+		require.Equal(t, http.StatusOK, httpRecorder.Code)
+		var status authFailureStatus
+		require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &status))
+		return status
+	}
+	confirmAuth := func(success bool) {
+		httpRecorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(httpRecorder)
+		authEvent := models.Udm_UEAU_AuthEvent{Success: success}
+		testProcessor.ConfirmAuthDataProcedure(c, authEvent, supi)
+		require.Equal(t, http.StatusCreated, httpRecorder.Code)
+		require.True(t, strings.HasSuffix(httpRecorder.Header().Get("Location"),
+			"/auth-events/"+udm_context.AuthEventId(supi, &authEvent)))
+	}
+
+	// AUTS whose MAC-S is wrong
+	badResync := &models.Udm_UEAU_ResynchronizationInfo{
+		Rand: "23553cbe9637a89d218ae64dae47bf35",
+		Auts: "0000000000000000000000000000",
+	}
+
+	// failed confirmations are counted and a successful one clears the counters
+	confirmAuth(false)
+	confirmAuth(false)
+	require.Equal(t, 2, authFailure().ConfirmationFailures)
+	// the counters are kept apart from the UE contexts
+	_, ok := udmContext.UdmUeFindBySupi(supi)
+	require.False(t, ok)
+	confirmAuth(true)
+	require.Equal(t, 0, authFailure().ConfirmationFailures)
+
+	problemDetails := generateAuthData(badResync)
+	require.Equal(t, "AUTHENTICATION_REJECTED", problemDetails.Cause)
+	status := authFailure()
+	require.Equal(t, 1, status.ResyncFailures)
+	require.False(t, status.LockedOut)
+
+	// the threshold is hit, the UE is rejected even without re-synchronization
+	problemDetails = generateAuthData(badResync)
+	require.Equal(t, "AUTHENTICATION_REJECTED", problemDetails.Cause)
+	problemDetails = generateAuthData(nil)
+	require.Equal(t, "AUTHENTICATION_LOCKED_OUT", problemDetails.Cause)
+
+	status = authFailure()
+	require.True(t, status.LockedOut)
+	require.NotNil(t, status.LockedUntil)
+
+	httpRecorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(httpRecorder)
+	testProcessor.GetAuthFailuresProcedure(c)
+	var statuses []authFailureStatus
+	require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &statuses))
+	require.Len(t, statuses, 1)
+	require.Equal(t, supi, statuses[0].Supi)
+
+	// the operator lifts the lockout
+	httpRecorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(httpRecorder)
+	testProcessor.DeleteAuthFailureProcedure(c, supi)
+	c.Writer.WriteHeaderNow()
+	require.Equal(t, http.StatusNoContent, httpRecorder.Code)
+	status = authFailure()
+	require.False(t, status.LockedOut)
+	require.Nil(t, status.LockedUntil)
+	require.Equal(t, 0, status.ResyncFailures)
+}
+
+func TestGenerateAuthDataProcedure_ServingNetworkAuthorization(t *testing.T) {
+	const supi = "imsi-208930000000001"
+	policy := &servingnetwork.Policy{
+		HomeNetworks:      []servingnetwork.Network{{Mcc: "208", Mnc: "93"}},
+		RoamingAgreements: []servingnetwork.Network{{Mcc: "466", Mnc: "92"}},
+	}
+
+	testCases := []struct {
+		name               string
+		servingNetworkName string
+		policy             *servingnetwork.Policy
+		amData             *models.Udm_SDM_AccessAndMobilitySubscriptionData
+		rawAmData          string // sent instead of amData when set
+		expectAmQuery      bool
+		expectStatus       int
+	}{
+		{
+			name:               "malformed serving network name",
+			servingNetworkName: "internet",
+			expectStatus:       http.StatusForbidden,
+		},
+		{
+			name:               "no policy configured",
+			servingNetworkName: "5G:mnc001.mcc001.3gppnetwork.org",
+			expectStatus:       http.StatusOK,
+		},
+		{
+			name:               "home network",
+			servingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org",
+			policy:             policy,
+			expectStatus:       http.StatusOK,
+		},
+		{
+			name:               "no roaming agreement",
+			servingNetworkName: "5G:mnc001.mcc001.3gppnetwork.org",
+			policy:             policy,
+			expectStatus:       http.StatusForbidden,
+		},
+		{
+			name:               "roaming allowed",
+			servingNetworkName: "5G:mnc092.mcc466.3gppnetwork.org",
+			policy:             policy,
+			amData: &models.Udm_SDM_AccessAndMobilitySubscriptionData{
+				RoamingRestrictions: &models.RoamingRestrictions{AccessAllowed: true},
+			},
+			expectAmQuery: true,
+			expectStatus:  http.StatusOK,
+		},
+		{
+			name:               "roaming restricted by AM data",
+			servingNetworkName: "5G:mnc092.mcc466.3gppnetwork.org",
+			policy:             policy,
+			amData: &models.Udm_SDM_AccessAndMobilitySubscriptionData{
+				RoamingRestrictions: &models.RoamingRestrictions{AccessAllowed: false},
+			},
+			expectAmQuery: true,
+			expectStatus:  http.StatusForbidden,
+		},
+		{
+			name:               "roaming restriction without accessAllowed",
+			servingNetworkName: "5G:mnc092.mcc466.3gppnetwork.org",
+			policy:             policy,
+			rawAmData:          `{"roamingRestrictions":{}}`,
+			expectAmQuery:      true,
+			expectStatus:       http.StatusForbidden,
+		},
+		{
+			name:               "AM data without roaming restriction",
+			servingNetworkName: "5G:mnc092.mcc466.3gppnetwork.org",
+			policy:             policy,
+			rawAmData:          `{"gpsis":["msisdn-0900000000"]}`,
+			expectAmQuery:      true,
+			expectStatus:       http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer gock.Off()
+			openapi.InterceptInnerHttp2Client(t, false)
+
+			if tc.expectAmQuery {
+				amDataMock := gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+					Get("/subscription-data/"+supi+"/46692/provisioned-data/am-data").
+					Reply(200).
+					AddHeader("Content-Type", "application/json")
+				if tc.rawAmData != "" {
+					amDataMock.BodyString(tc.rawAmData)
+				} else {
+					amDataMock.JSON(tc.amData)
+				}
+			}
+			if tc.expectStatus == http.StatusOK {
+				gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+					Get("/subscription-data/"+supi+"/authentication-data/authentication-subscription").
+					Reply(200).
+					AddHeader("Content-Type", "application/json").
+					JSON(models.Udr_DR_AuthenticationSubscription{
+						AuthenticationMethod:          models.Udr_DR_AuthMethod_5_G_AKA,
+						EncPermanentKey:               "8baf473f2f8fd09487cccbd7097c6862",
+						SequenceNumber:                &models.Udr_DR_SequenceNumber{Sqn: "000000000023"},
+						AuthenticationManagementField: "8000",
+						EncOpcKey:                     "8e27b6af0e692e750f32667a3b14605d",
+					})
+				gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+					Patch("/subscription-data/" + supi + "/authentication-data/authentication-subscription").
+					Reply(204)
+			}
+
+			testProcessor := newTestProcessor(t, &udm_context.UDMContext{
+				NrfUri:               "http://127.0.0.10:8000",
+				NfId:                 "1",
+				ServingNetworkPolicy: tc.policy,
+			}, supi)
+
+			authInfoReq := models.Udm_UEAU_AuthenticationInfoRequest{
+				ServingNetworkName: tc.servingNetworkName,
+			}
+			httpRecorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(httpRecorder)
+			testProcessor.GenerateAuthDataProcedure(c, authInfoReq, supi)
+
+			require.Equal(t, tc.expectStatus, httpRecorder.Code)
+			if tc.expectStatus == http.StatusForbidden {
+				require.Contains(t, httpRecorder.Body.String(), "SERVING_NETWORK_NOT_AUTHORIZED")
+			}
+			require.True(t, gock.IsDone())
+		})
+	}
+}
+
+func TestDeleteAuthProcedure(t *testing.T) {
+	const supi = "imsi-208930000000001"
+	const servingNetworkName = "5G:mnc093.mcc208.3gppnetwork.org"
+	timeStamp := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	storedAuthEvent := models.Udm_UEAU_AuthEvent{
+		NfInstanceId:       "ausf-1",
+		Success:            true,
+		TimeStamp:          &timeStamp,
+		AuthType:           models.Udm_UEAU_AuthType_5_G_AKA,
+		ServingNetworkName: servingNetworkName,
+	}
+	// the authEventId the confirmation of the stored event returned
+	authEventId := udm_context.AuthEventId(supi, &storedAuthEvent)
+
+	testCases := []struct {
+		name               string
+		servingNetworkName string
+		authEventId        string
+		expectDelete       bool
+		expectStatus       int
+	}{
+		{
+			name:               "matching auth event is removed",
+			servingNetworkName: servingNetworkName,
+			authEventId:        authEventId,
+			expectDelete:       true,
+			expectStatus:       204,
+		},
+		{
+			name:               "unknown auth event",
+			servingNetworkName: "5G:mnc001.mcc001.3gppnetwork.org",
+			authEventId:        authEventId,
+			expectDelete:       false,
+			expectStatus:       404,
+		},
+		{
+			name:               "unknown authEventId",
+			servingNetworkName: servingNetworkName,
+			authEventId:        "1",
+			expectDelete:       false,
+			expectStatus:       404,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer gock.Off()
+			openapi.InterceptInnerHttp2Client(t, false)
+
+			gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+				Get("/subscription-data/"+supi+"/authentication-data/authentication-status").
+				Reply(200).
+				AddHeader("Content-Type", "application/json").
+				JSON(storedAuthEvent)
+			if tc.expectDelete {
+				gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+					Delete("/subscription-data/" + supi + "/authentication-data/authentication-status").
+					Reply(204)
+			}
+
+			testProcessor := newTestProcessor(t, &udm_context.UDMContext{
+				NrfUri: "http://127.0.0.10:8000",
+				NfId:   "1",
+			}, supi)
+
+			authEvent := models.Udm_UEAU_AuthEvent{
+				NfInstanceId:       "ausf-1",
+				AuthType:           models.Udm_UEAU_AuthType_5_G_AKA,
+				ServingNetworkName: tc.servingNetworkName,
+				AuthRemovalInd:     true,
+			}
+			httpRecorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(httpRecorder)
+			testProcessor.DeleteAuthProcedure(c, authEvent, supi, tc.authEventId)
+
+			require.Equal(t, tc.expectStatus, c.Writer.Status())
+			if !tc.expectDelete {
+				require.Contains(t, httpRecorder.Body.String(), "DATA_NOT_FOUND")
+			}
+			require.True(t, gock.IsDone())
+		})
+	}
+}
+
+func TestGenerateGbaAvProcedure(t *testing.T) {
+	defer gock.Off()
+	openapi.InterceptInnerHttp2Client(t, false)
+
+	supi := "imsi-208930000000001"
+	k := "8baf473f2f8fd09487cccbd7097c6862"
+	opc := "8e27b6af0e692e750f32667a3b14605d"
+	queryRes := models.Udr_DR_AuthenticationSubscription{
+		AuthenticationMethod:          models.Udr_DR_AuthMethod_5_G_AKA,
+		EncPermanentKey:               k,
+		SequenceNumber:                &models.Udr_DR_SequenceNumber{Sqn: "000000000023"},
+		AuthenticationManagementField: "8000",
+		EncOpcKey:                     opc,
+	}
+
+	gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+		Get("/subscription-data/"+supi+"/authentication-data/authentication-subscription").
+		Reply(200).
+		AddHeader("Content-Type", "application/json").
+		JSON(queryRes)
+
+	// the SQN written back to the UDR follows the 5G AKA rules
+	gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+		Patch("/subscription-data/" + supi + "/authentication-data/authentication-subscription").
+		BodyString(`"sqn":"000000000024"`).
+		Reply(204)
+
+	testProcessor := newTestProcessor(t, &udm_context.UDMContext{
+		NrfUri: "http://127.0.0.10:8000",
+		NfId:   "1",
+	}, supi)
+
+	gbaAuthInfoReq := models.Udm_UEAU_GbaAuthenticationInfoRequest{
+		AuthType: models.Udm_UEAU_GbaAuthType_DIGEST_AKAV1_MD5,
+	}
+	httpRecorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(httpRecorder)
+	testProcessor.GenerateGbaAvProcedure(c, gbaAuthInfoReq, supi)
+
+	require.Equal(t, 200, httpRecorder.Code)
+	require.True(t, gock.IsDone())
+
+	var res models.Udm_UEAU_GbaAuthenticationInfoResult
+	require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &res))
+	require.NotNil(t, res.Var3gAkaAv)
+
+	// RAND is random, recompute the vector from it with the stored SQN
+	kBytes, err := hex.DecodeString(k)
+	require.NoError(t, err)
+	opcBytes, err := hex.DecodeString(opc)
+	require.NoError(t, err)
+	randBytes, err := hex.DecodeString(res.Var3gAkaAv.Rand)
+	require.NoError(t, err)
+	sqnBytes, err := hex.DecodeString("000000000023")
+	require.NoError(t, err)
+	amfBytes, err := hex.DecodeString("8000")
+	require.NoError(t, err)
+	ik, ck, xres, autn, err := milenage.GenerateAKAParameters(opcBytes, kBytes, randBytes, sqnBytes, amfBytes)
+	require.NoError(t, err)
+
+	require.Equal(t, hex.EncodeToString(xres), res.Var3gAkaAv.Xres)
+	require.Equal(t, hex.EncodeToString(autn), res.Var3gAkaAv.Autn)
+	require.Equal(t, hex.EncodeToString(ck), res.Var3gAkaAv.Ck)
+	require.Equal(t, hex.EncodeToString(ik), res.Var3gAkaAv.Ik)
+}
+
+func TestGenerateAvProcedure(t *testing.T) {
+	defer gock.Off()
+	openapi.InterceptInnerHttp2Client(t, false)
+
+	supi := "imsi-208930000000001"
+	k := "8baf473f2f8fd09487cccbd7097c6862"
+	opc := "8e27b6af0e692e750f32667a3b14605d"
+	queryRes := models.Udr_DR_AuthenticationSubscription{
+		AuthenticationMethod:          models.Udr_DR_AuthMethod_5_G_AKA,
+		EncPermanentKey:               k,
+		SequenceNumber:                &models.Udr_DR_SequenceNumber{Sqn: "000000000023"},
+		AuthenticationManagementField: "8000",
+		EncOpcKey:                     opc,
+	}
+
+	gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+		Get("/subscription-data/"+supi+"/authentication-data/authentication-subscription").
+		Reply(200).
+		AddHeader("Content-Type", "application/json").
+		JSON(queryRes)
+
+	// a single update carrying the SQN following the last vector of the batch
+	gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+		Patch("/subscription-data/" + supi + "/authentication-data/authentication-subscription").
+		BodyString(`"sqn":"000000000026"`).
+		Times(1).
+		Reply(204)
+
+	testProcessor := newTestProcessor(t, &udm_context.UDMContext{
+		NrfUri: "http://127.0.0.10:8000",
+		NfId:   "1",
+	}, supi)
+
+	hssAuthInfoReq := models.Udm_UEAU_HssAuthenticationInfoRequest{
+		HssAuthType:           models.Udm_UEAU_HssAuthType_UMTS_AKA,
+		NumOfRequestedVectors: 3,
+	}
+	httpRecorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(httpRecorder)
+	testProcessor.GenerateAvProcedure(c, hssAuthInfoReq, supi)
+
+	require.Equal(t, 200, httpRecorder.Code)
+	require.True(t, gock.IsDone())
+
+	var res struct {
+		HssAuthenticationVectors []models.Udm_UEAU_AvImsGbaEapAka `json:"hssAuthenticationVectors"`
+	}
+	require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &res))
+	require.Len(t, res.HssAuthenticationVectors, 3)
+
+	kBytes, err := hex.DecodeString(k)
+	require.NoError(t, err)
+	opcBytes, err := hex.DecodeString(opc)
+	require.NoError(t, err)
+	amfBytes, err := hex.DecodeString("8000")
+	require.NoError(t, err)
+
+	// the vectors use consecutive SQNs starting from the stored one
+	for i, sqn := range []string{"000000000023", "000000000024", "000000000025"} {
+		av := res.HssAuthenticationVectors[i]
+		require.Equal(t, models.Udm_UEAU_HssAvType_UMTS_AKA, av.AvType)
+
+		randBytes, err := hex.DecodeString(av.Rand)
+		require.NoError(t, err)
+		sqnBytes, err := hex.DecodeString(sqn)
+		require.NoError(t, err)
+		ik, ck, xres, autn, err := milenage.GenerateAKAParameters(opcBytes, kBytes, randBytes, sqnBytes, amfBytes)
+		require.NoError(t, err)
+
+		require.Equal(t, hex.EncodeToString(xres), av.Xres)
+		require.Equal(t, hex.EncodeToString(autn), av.Autn)
+		require.Equal(t, hex.EncodeToString(ck), av.Ck)
+		require.Equal(t, hex.EncodeToString(ik), av.Ik)
+	}
+}
+
+func TestGenerateAvProcedure_EpsAndImsAka(t *testing.T) {
+	supi := "imsi-208930000000001"
+	k := "8baf473f2f8fd09487cccbd7097c6862"
+	opc := "8e27b6af0e692e750f32667a3b14605d"
+	kBytes, err := hex.DecodeString(k)
+	require.NoError(t, err)
+	opcBytes, err := hex.DecodeString(opc)
+	require.NoError(t, err)
+	sqnBytes, err := hex.DecodeString("000000000023")
+	require.NoError(t, err)
+	amfBytes, err := hex.DecodeString("8000")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name           string
+		hssAuthInfoReq models.Udm_UEAU_HssAuthenticationInfoRequest
+		expectedStatus int
+		expectedSnId   string
+	}{
+		{
+			name: "EPS AKA with a 2 digit MNC",
+			hssAuthInfoReq: models.Udm_UEAU_HssAuthenticationInfoRequest{
+				HssAuthType:           models.Udm_UEAU_HssAuthType_EPS_AKA,
+				NumOfRequestedVectors: 1,
+				ServingNetworkId:      &models.PlmnId{Mcc: "208", Mnc: "93"},
+			},
+			expectedStatus: http.StatusOK,
+			expectedSnId:   "02f839",
+		},
+		{
+			name: "EPS AKA with a 3 digit MNC",
+			hssAuthInfoReq: models.Udm_UEAU_HssAuthenticationInfoRequest{
+				HssAuthType:           models.Udm_UEAU_HssAuthType_EPS_AKA,
+				NumOfRequestedVectors: 1,
+				ServingNetworkId:      &models.PlmnId{Mcc: "310", Mnc: "410"},
+			},
+			expectedStatus: http.StatusOK,
+			expectedSnId:   "130014",
+		},
+		{
+			name: "EPS AKA without serving network",
+			hssAuthInfoReq: models.Udm_UEAU_HssAuthenticationInfoRequest{
+				HssAuthType:           models.Udm_UEAU_HssAuthType_EPS_AKA,
+				NumOfRequestedVectors: 1,
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "IMS AKA",
+			hssAuthInfoReq: models.Udm_UEAU_HssAuthenticationInfoRequest{
+				HssAuthType:           models.Udm_UEAU_HssAuthType_IMS_AKA,
+				NumOfRequestedVectors: 1,
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer gock.Off()
+			openapi.InterceptInnerHttp2Client(t, false)
+
+			if tc.expectedStatus == http.StatusOK {
+				gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+					Get("/subscription-data/"+supi+"/authentication-data/authentication-subscription").
+					Reply(200).
+					AddHeader("Content-Type", "application/json").
+					JSON(models.Udr_DR_AuthenticationSubscription{
+						AuthenticationMethod:          models.Udr_DR_AuthMethod_5_G_AKA,
+						EncPermanentKey:               k,
+						SequenceNumber:                &models.Udr_DR_SequenceNumber{Sqn: "000000000023"},
+						AuthenticationManagementField: "8000",
+						EncOpcKey:                     opc,
+					})
+
+				gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+					Patch("/subscription-data/" + supi + "/authentication-data/authentication-subscription").
+					Reply(204)
+			}
+
+			testProcessor := newTestProcessor(t, &udm_context.UDMContext{
+				NrfUri: "http://127.0.0.10:8000",
+				NfId:   "1",
+			}, supi)
+
+			httpRecorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(httpRecorder)
+			testProcessor.GenerateAvProcedure(c, tc.hssAuthInfoReq, supi)
+
+			require.Equal(t, tc.expectedStatus, httpRecorder.Code)
+			require.True(t, gock.IsDone())
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var res struct {
+				HssAuthenticationVectors []struct {
+					models.Udm_UEAU_AvImsGbaEapAka
+					Kasme string `json:"kasme"`
+				} `json:"hssAuthenticationVectors"`
+			}
+			require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &res))
+			require.Len(t, res.HssAuthenticationVectors, 1)
+			av := res.HssAuthenticationVectors[0]
+			require.Equal(t, models.Udm_UEAU_HssAvType(tc.hssAuthInfoReq.HssAuthType), av.AvType)
+
+			randBytes, err := hex.DecodeString(av.Rand)
+			require.NoError(t, err)
+			ik, ck, xres, autn, err := milenage.GenerateAKAParameters(opcBytes, kBytes, randBytes, sqnBytes, amfBytes)
+			require.NoError(t, err)
+			require.Equal(t, hex.EncodeToString(xres), av.Xres)
+			require.Equal(t, hex.EncodeToString(autn), av.Autn)
+
+			if tc.hssAuthInfoReq.HssAuthType == models.Udm_UEAU_HssAuthType_IMS_AKA {
+				require.Equal(t, hex.EncodeToString(ck), av.Ck)
+				require.Equal(t, hex.EncodeToString(ik), av.Ik)
+				require.Empty(t, av.Kasme)
+				return
+			}
+
+			// KASME = KDF(CK || IK, FC = 0x10, SN id, SQN xor AK)
+			snId, err := hex.DecodeString(tc.expectedSnId)
+			require.NoError(t, err)
+			kasme, err := ueauth.GetKDFValue(append(ck, ik...), "10",
+				snId, ueauth.KDFLen(snId), autn[:6], ueauth.KDFLen(autn[:6]))
+			require.NoError(t, err)
+			require.Equal(t, hex.EncodeToString(kasme), av.Kasme)
+			require.Empty(t, av.Ck)
+		})
+	}
+}
+
+func TestGenerateProseAVProcedure(t *testing.T) {
+	defer gock.Off()
+	openapi.InterceptInnerHttp2Client(t, false)
+
+	supi := "imsi-208930000000001"
+	queryRes := models.Udr_DR_AuthenticationSubscription{
+		AuthenticationMethod:          models.Udr_DR_AuthMethod_EAP_AKA_PRIME,
+		EncPermanentKey:               "8baf473f2f8fd09487cccbd7097c6862",
+		SequenceNumber:                &models.Udr_DR_SequenceNumber{Sqn: "000000000023"},
+		AuthenticationManagementField: "8000",
+		EncOpcKey:                     "8e27b6af0e692e750f32667a3b14605d",
+	}
+
+	gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+		Get("/subscription-data/"+supi+"/authentication-data/authentication-subscription").
+		Reply(200).
+		AddHeader("Content-Type", "application/json").
+		JSON(queryRes)
+
+	gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+		Patch("/subscription-data/" + supi + "/authentication-data/authentication-subscription").
+		BodyString(`"sqn":"000000000024"`).
+		Reply(204)
+
+	testProcessor := newTestProcessor(t, &udm_context.UDMContext{
+		NrfUri: "http://127.0.0.10:8000",
+		NfId:   "1",
+	}, supi)
+
+	proseAuthInfoReq := models.Udm_UEAU_ProSeAuthenticationInfoRequest{
+		ServingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org",
+		RelayServiceCode:   1,
+	}
+	httpRecorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(httpRecorder)
+	testProcessor.GenerateProseAVProcedure(c, proseAuthInfoReq, supi)
+
+	require.Equal(t, 200, httpRecorder.Code)
+	require.True(t, gock.IsDone())
+
+	var res proSeAuthenticationInfoResult
+	require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &res))
+	require.Equal(t, models.Udm_UEAU_AuthType_EAP_AKA_PRIME, res.AuthType)
+	require.Equal(t, supi, res.Supi)
+	require.Len(t, res.ProseAuthenticationVectors, 1)
+	av := res.ProseAuthenticationVectors[0]
+	require.Equal(t, models.Udm_UEAU_AvType_EAP_AKA_PRIME, av.AvType)
+	require.Len(t, av.CkPrime, 32)
+	require.Len(t, av.IkPrime, 32)
+}
+
+func TestGetRgAuthDataProcedure(t *testing.T) {
+	supi := "imsi-208930000000001"
+
+	testCases := []struct {
+		name             string
+		supiOrSuci       string
+		authSubscription models.Udr_DR_AuthenticationSubscription
+		authenticatedInd bool
+		expectedStatus   int
+		expectedAuthInd  bool
+	}{
+		{
+			name:       "5G-RG with credentials",
+			supiOrSuci: supi,
+			authSubscription: models.Udr_DR_AuthenticationSubscription{
+				AuthenticationMethod: models.Udr_DR_AuthMethod_5_G_AKA,
+				EncPermanentKey:      "8baf473f2f8fd09487cccbd7097c6862",
+			},
+			authenticatedInd: true,
+			expectedStatus:   http.StatusOK,
+			expectedAuthInd:  false,
+		},
+		{
+			name:             "FN-RG authenticated by the access network (null scheme SUCI)",
+			supiOrSuci:       "suci-0-208-93-0000-0-0-0000000001",
+			authSubscription: models.Udr_DR_AuthenticationSubscription{},
+			authenticatedInd: true,
+			expectedStatus:   http.StatusOK,
+			expectedAuthInd:  true,
+		},
+		{
+			name:             "FN-RG not authenticated",
+			supiOrSuci:       supi,
+			authSubscription: models.Udr_DR_AuthenticationSubscription{},
+			authenticatedInd: false,
+			expectedStatus:   http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer gock.Off()
+			openapi.InterceptInnerHttp2Client(t, false)
+
+			gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+				Get("/subscription-data/"+supi+"/authentication-data/authentication-subscription").
+				Reply(200).
+				AddHeader("Content-Type", "application/json").
+				JSON(tc.authSubscription)
+
+			testProcessor := newTestProcessor(t, &udm_context.UDMContext{
+				NrfUri: "http://127.0.0.10:8000",
+				NfId:   "1",
+			}, supi)
+
+			httpRecorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(httpRecorder)
+			testProcessor.GetRgAuthDataProcedure(c, tc.supiOrSuci, tc.authenticatedInd, "")
+
+			require.Equal(t, tc.expectedStatus, httpRecorder.Code)
+			require.True(t, gock.IsDone())
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var rgAuthCtx models.Udm_UEAU_RgAuthCtx
+			require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &rgAuthCtx))
+			require.Equal(t, supi, rgAuthCtx.Supi)
+			require.Equal(t, tc.expectedAuthInd, rgAuthCtx.AuthInd)
+		})
+	}
+}
+
+func TestGenerateAuthDataProcedure_AkaAlgorithms(t *testing.T) {
+	supi := "imsi-208930000000001"
+
+	// K and OPc encrypted with AES-128-ECB under the keystore key of protection parameter "1"
+	keyStore, err := keyprovider.NewFileKeyStore([]keyprovider.FileKey{
+		{Id: "1", Algorithm: keyprovider.AlgorithmAesEcb, Key: "000102030405060708090a0b0c0d0e0f"},
+	})
+	require.NoError(t, err)
+	block, err := aes.NewCipher([]byte{
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+	})
+	require.NoError(t, err)
+	encrypt := func(plaintext string) string {
+		raw, errDecode := hex.DecodeString(plaintext)
+		require.NoError(t, errDecode)
+		block.Encrypt(raw, raw)
+		return hex.EncodeToString(raw)
+	}
+
+	testCases := []struct {
+		name             string
+		authSubscription models.Udr_DR_AuthenticationSubscription
+		operatorOp       string
+		keyProvider      keyprovider.KeyProvider
+		plaintextKeyIds  []string
+		expectedStatus   int
+		expectedDetail   string
+	}{
+		{
+			name: "TUAK with TOPc",
+			authSubscription: models.Udr_DR_AuthenticationSubscription{
+				AuthenticationMethod:          models.Udr_DR_AuthMethod_5_G_AKA,
+				AlgorithmId:                   "tuak",
+				EncPermanentKey:               "abababababababababababababababababababababababababababababababab",
+				EncTopcKey:                    "bd04d9530e87513c5d837ac2ad954623a8e2330c115305a73eb45d1f40cccbff",
+				SequenceNumber:                &models.Udr_DR_SequenceNumber{Sqn: "000000000023"},
+				AuthenticationManagementField: "8000",
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "test algorithm without operator key",
+			authSubscription: models.Udr_DR_AuthenticationSubscription{
+				AuthenticationMethod:          models.Udr_DR_AuthMethod_5_G_AKA,
+				AlgorithmId:                   "xor",
+				EncPermanentKey:               "8baf473f2f8fd09487cccbd7097c6862",
+				SequenceNumber:                &models.Udr_DR_SequenceNumber{Sqn: "000000000023"},
+				AuthenticationManagementField: "8000",
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "unknown algorithm",
+			authSubscription: models.Udr_DR_AuthenticationSubscription{
+				AuthenticationMethod:          models.Udr_DR_AuthMethod_5_G_AKA,
+				AlgorithmId:                   "128-EEA0",
+				EncPermanentKey:               "8baf473f2f8fd09487cccbd7097c6862",
+				EncOpcKey:                     "8e27b6af0e692e750f32667a3b14605d",
+				SequenceNumber:                &models.Udr_DR_SequenceNumber{Sqn: "000000000023"},
+				AuthenticationManagementField: "8000",
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedDetail: "unsupported AlgorithmId [128-EEA0]",
+		},
+		{
+			name: "milenage without OPc",
+			authSubscription: models.Udr_DR_AuthenticationSubscription{
+				AuthenticationMethod:          models.Udr_DR_AuthMethod_5_G_AKA,
+				AlgorithmId:                   "milenage",
+				EncPermanentKey:               "8baf473f2f8fd09487cccbd7097c6862",
+				SequenceNumber:                &models.Udr_DR_SequenceNumber{Sqn: "000000000023"},
+				AuthenticationManagementField: "8000",
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedDetail: "EncOpcKey is not provisioned for the subscription and no operator OP is configured",
+		},
+		{
+			name: "milenage with operator OP",
+			authSubscription: models.Udr_DR_AuthenticationSubscription{
+				AuthenticationMethod:          models.Udr_DR_AuthMethod_5_G_AKA,
+				AlgorithmId:                   "milenage",
+				EncPermanentKey:               "8baf473f2f8fd09487cccbd7097c6862",
+				SequenceNumber:                &models.Udr_DR_SequenceNumber{Sqn: "000000000023"},
+				AuthenticationManagementField: "8000",
+			},
+			operatorOp:     "cdc202d5123e20f62b6d676ac72cb318",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "milenage with keys encrypted at rest",
+			authSubscription: models.Udr_DR_AuthenticationSubscription{
+				AuthenticationMethod:          models.Udr_DR_AuthMethod_5_G_AKA,
+				AlgorithmId:                   "milenage",
+				EncPermanentKey:               encrypt("8baf473f2f8fd09487cccbd7097c6862"),
+				EncOpcKey:                     encrypt("8e27b6af0e692e750f32667a3b14605d"),
+				ProtectionParameterId:         "1",
+				SequenceNumber:                &models.Udr_DR_SequenceNumber{Sqn: "000000000023"},
+				AuthenticationManagementField: "8000",
+			},
+			keyProvider:    keyStore,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "unknown protection parameter",
+			authSubscription: models.Udr_DR_AuthenticationSubscription{
+				AuthenticationMethod:          models.Udr_DR_AuthMethod_5_G_AKA,
+				AlgorithmId:                   "milenage",
+				EncPermanentKey:               encrypt("8baf473f2f8fd09487cccbd7097c6862"),
+				EncOpcKey:                     encrypt("8e27b6af0e692e750f32667a3b14605d"),
+				ProtectionParameterId:         "2",
+				SequenceNumber:                &models.Udr_DR_SequenceNumber{Sqn: "000000000023"},
+				AuthenticationManagementField: "8000",
+			},
+			keyProvider:    keyStore,
+			expectedStatus: http.StatusForbidden,
+			expectedDetail: "EncPermanentKey: no key for protection parameter [2]",
+		},
+		{
+			name: "configured plaintext protection parameter",
+			authSubscription: models.Udr_DR_AuthenticationSubscription{
+				AuthenticationMethod:          models.Udr_DR_AuthMethod_5_G_AKA,
+				AlgorithmId:                   "milenage",
+				EncPermanentKey:               "8baf473f2f8fd09487cccbd7097c6862",
+				EncOpcKey:                     "8e27b6af0e692e750f32667a3b14605d",
+				ProtectionParameterId:         "plaintext",
+				SequenceNumber:                &models.Udr_DR_SequenceNumber{Sqn: "000000000023"},
+				AuthenticationManagementField: "8000",
+			},
+			keyProvider:     keyStore,
+			plaintextKeyIds: []string{"plaintext"},
+			expectedStatus:  http.StatusOK,
+		},
+		{
+			// a protection parameter ID equal to K no longer marks plaintext keys
+			name: "permanent key as protection parameter",
+			authSubscription: models.Udr_DR_AuthenticationSubscription{
+				AuthenticationMethod:          models.Udr_DR_AuthMethod_5_G_AKA,
+				AlgorithmId:                   "milenage",
+				EncPermanentKey:               "8baf473f2f8fd09487cccbd7097c6862",
+				EncOpcKey:                     "8e27b6af0e692e750f32667a3b14605d",
+				ProtectionParameterId:         "8baf473f2f8fd09487cccbd7097c6862",
+				SequenceNumber:                &models.Udr_DR_SequenceNumber{Sqn: "000000000023"},
+				AuthenticationManagementField: "8000",
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedDetail: "EncPermanentKey: no key provider is configured for protection parameter " +
+				"[8baf473f2f8fd09487cccbd7097c6862]",
+		},
+		{
+			name: "keys encrypted at rest without key provider",
+			authSubscription: models.Udr_DR_AuthenticationSubscription{
+				AuthenticationMethod:          models.Udr_DR_AuthMethod_5_G_AKA,
+				AlgorithmId:                   "milenage",
+				EncPermanentKey:               encrypt("8baf473f2f8fd09487cccbd7097c6862"),
+				EncOpcKey:                     encrypt("8e27b6af0e692e750f32667a3b14605d"),
+				ProtectionParameterId:         "1",
+				SequenceNumber:                &models.Udr_DR_SequenceNumber{Sqn: "000000000023"},
+				AuthenticationManagementField: "8000",
+			},
+			expectedStatus: http.StatusForbidden,
+			expectedDetail: "EncPermanentKey: no key provider is configured for protection parameter [1]",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer gock.Off()
+			openapi.InterceptInnerHttp2Client(t, false)
+
+			gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+				Get("/subscription-data/"+supi+"/authentication-data/authentication-subscription").
+				Reply(200).
+				AddHeader("Content-Type", "application/json").
+				JSON(tc.authSubscription)
+
+			if tc.expectedStatus == http.StatusOK {
+				gock.New("http://127.0.0.4:8000/nudr-dr/v2").
+					Patch("/subscription-data/" + supi + "/authentication-data/authentication-subscription").
+					Reply(204)
+			}
+
+			testProcessor := newTestProcessor(t, &udm_context.UDMContext{
+				NrfUri:          "http://127.0.0.10:8000",
+				NfId:            "1",
+				OperatorOp:      tc.operatorOp,
+				KeyProvider:     tc.keyProvider,
+				PlaintextKeyIds: tc.plaintextKeyIds,
+			}, supi)
+
+			authInfoReq := models.Udm_UEAU_AuthenticationInfoRequest{
+				ServingNetworkName: "5G:mnc093.mcc208.3gppnetwork.org",
+			}
+			httpRecorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(httpRecorder)
+			testProcessor.GenerateAuthDataProcedure(c, authInfoReq, supi)
+
+			require.Equal(t, tc.expectedStatus, httpRecorder.Code)
+			require.True(t, gock.IsDone())
+			if tc.expectedDetail != "" {
+				var problemDetails models.ProblemDetails
+				require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &problemDetails))
+				require.Equal(t, tc.expectedDetail, problemDetails.Detail)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package processor
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -82,10 +83,23 @@ func (p *Processor) SendOnDeregistrationNotification(ueId string, onDeregistrati
 		if apiErr, ok := err.(openapi.GenericOpenAPIError); ok {
 			// API error
 			if deregisterNoti_err, ok2 := apiErr.
-				Model().(UECM.Call3GppRegistrationDeregistrationNotificationError); ok2 {
+				Model().(UECM.Call3GppRegistrationDeregistrationNotificationError); ok2 &&
+				deregisterNoti_err.ProblemDetails != nil {
 				return deregisterNoti_err.ProblemDetails
 			}
+			// the AMF answered with a status the client has no model for
+			problemDetails := &models.ProblemDetails{}
+			if json.Unmarshal(apiErr.RawBody, problemDetails) != nil || problemDetails.Status == 0 {
+				problemDetails = &models.ProblemDetails{
+					Title:  http.StatusText(apiErr.ErrorStatus),
+					Status: int32(apiErr.ErrorStatus),
+					Detail: "deregistration notification rejected by the AMF",
+				}
+			}
+			return problemDetails
 		}
+		logger.HttpLog.Error(err.Error())
+		return openapi.ProblemDetailsSystemFailure(err.Error())
 	}
 
 	return nil
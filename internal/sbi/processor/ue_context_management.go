@@ -519,6 +519,7 @@ func (p *Processor) DeregistrationSmfRegistrationsProcedure(c *gin.Context,
 		return
 	}
 
+	p.Context().DeleteSmfRegContext(ueID, pduSessionIDInt32)
 	c.Status(http.StatusNoContent)
 }
 
@@ -534,17 +535,15 @@ func (p *Processor) RegistrationSmfRegistrationsProcedure(
 		c.JSON(int(pd.Status), pd)
 		return
 	}
-	contextExisted := false
-	p.Context().CreateSmfRegContext(ueID, pduSessionID)
-	if !p.Context().UdmSmfRegContextNotExists(ueID) {
-		contextExisted = true
-	}
-
 	pduID64, err := strconv.ParseInt(pduSessionID, 10, 32)
 	if err != nil {
-		logger.UecmLog.Errorln(err.Error())
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
 	}
 	pduID32 := int32(pduID64)
+	contextExisted := !p.Context().UdmSmfRegContextNotExists(ueID, pduID32)
 
 	var createSmfContext3gppRequest Nudr_DataRepository.CreateOrUpdateSmfRegistrationRequest
 	createSmfContext3gppRequest.UeId = &ueID
@@ -572,11 +571,788 @@ func (p *Processor) RegistrationSmfRegistrationsProcedure(
 		return
 	}
 
+	p.Context().CreateSmfRegContext(ueID, pduID32, *smfRegistration)
+
 	if contextExisted {
 		c.Status(http.StatusNoContent)
 	} else {
 		udmUe, _ := p.Context().UdmUeFindBySupi(ueID)
-		c.Header("Location", udmUe.GetLocationURI(udm_context.LocationUriSmfRegistration))
+		c.Header("Location", udmUe.GetPduSessionLocationURI(udm_context.LocationUriSmfRegistration, pduID32))
 		c.JSON(http.StatusCreated, smfRegistration)
 	}
 }
+
+func (p *Processor) UpdateSmfRegistrationProcedure(c *gin.Context,
+	request models.Udm_UECM_SmfRegistrationModification,
+	ueID string,
+	pduSessionID string,
+) {
+	ctx, pd, err := p.Context().GetTokenCtx(models.Nrf_NFMgmt_ServiceName_NUDR_DR, models.Nrf_NFMgmt_NFType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+	pduID64, err := strconv.ParseInt(pduSessionID, 10, 32)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+	pduID32 := int32(pduID64)
+
+	clientAPI, err := p.Consumer().CreateUDMClientToUDR(ueID)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	currentContext := p.Context().GetSmfRegContext(ueID, pduID32)
+	if currentContext == nil {
+		// the registration may predate a restart of the UDM, the UDR holds it then
+		var querySmfRegistrationRequest Nudr_DataRepository.QuerySmfRegistrationRequest
+		querySmfRegistrationRequest.UeId = &ueID
+		querySmfRegistrationRequest.PduSessionId = &pduID32
+		smfRegistration, errQuery := clientAPI.SMFRegistrationDocumentApi.
+			QuerySmfRegistration(ctx, &querySmfRegistrationRequest)
+		if errQuery != nil && !udrHasNoData(errQuery) {
+			if apiErr, ok := errQuery.(openapi.GenericOpenAPIError); ok {
+				c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(apiErr.ErrorStatus))
+				c.Data(apiErr.ErrorStatus, "application/json", apiErr.RawBody)
+				return
+			}
+			problemDetails := openapi.ProblemDetailsSystemFailure(errQuery.Error())
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+			c.JSON(int(problemDetails.Status), problemDetails)
+			return
+		}
+		if errQuery != nil || smfRegistration == nil || smfRegistration.Udm_UECM_SmfRegistration == nil {
+			logger.UecmLog.Errorf("[UpdateSmfRegistration] No SmfRegContext of PDU session [%s]", pduSessionID)
+			problemDetails := &models.ProblemDetails{
+				Status: http.StatusNotFound,
+				Cause:  "CONTEXT_NOT_FOUND",
+			}
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+			c.JSON(int(problemDetails.Status), problemDetails)
+			return
+		}
+		currentContext = smfRegistration.Udm_UECM_SmfRegistration
+		p.Context().CreateSmfRegContext(ueID, pduID32, *currentContext)
+	}
+
+	var patchItemReqArray []models.PatchItem
+	if request.SmfInstanceId != currentContext.SmfInstanceId {
+		logger.UecmLog.Infof("UpdateSmfRegistration: SMF [%s] takes over PDU session [%s] from SMF [%s]",
+			request.SmfInstanceId, pduSessionID, currentContext.SmfInstanceId)
+		var patchItemTmp models.PatchItem
+		patchItemTmp.Path = "/" + "smfInstanceId"
+		patchItemTmp.Op = models.PatchOperation_REPLACE
+		patchItemTmp.Value = request.SmfInstanceId
+		patchItemReqArray = append(patchItemReqArray, patchItemTmp)
+	}
+
+	if request.SmfSetId != "" {
+		var patchItemTmp models.PatchItem
+		patchItemTmp.Path = "/" + "smfSetId"
+		patchItemTmp.Op = models.PatchOperation_REPLACE
+		patchItemTmp.Value = request.SmfSetId
+		patchItemReqArray = append(patchItemReqArray, patchItemTmp)
+	}
+
+	if request.PgwFqdn != "" {
+		var patchItemTmp models.PatchItem
+		patchItemTmp.Path = "/" + "pgwFqdn"
+		patchItemTmp.Op = models.PatchOperation_REPLACE
+		patchItemTmp.Value = request.PgwFqdn
+		patchItemReqArray = append(patchItemReqArray, patchItemTmp)
+	}
+
+	if len(patchItemReqArray) > 0 {
+		var updateSmfContextRequest Nudr_DataRepository.UpdateSmfContextRequest
+		updateSmfContextRequest.UeId = &ueID
+		updateSmfContextRequest.PduSessionId = &pduID32
+		updateSmfContextRequest.RequestBody = patchItemReqArray
+		_, err = clientAPI.SMFRegistrationDocumentApi.UpdateSmfContext(ctx, &updateSmfContextRequest)
+		if err != nil {
+			if apiErr, ok := err.(openapi.GenericOpenAPIError); ok {
+				if updateSmfContextErr, ok2 := apiErr.Model().(Nudr_DataRepository.UpdateSmfContextError); ok2 &&
+					updateSmfContextErr.ProblemDetails != nil {
+					problem := updateSmfContextErr.ProblemDetails
+					c.Set(sbi.IN_PB_DETAILS_CTX_STR, problem.Cause)
+					c.JSON(int(problem.Status), problem)
+					return
+				}
+				c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(apiErr.ErrorStatus))
+				c.Data(apiErr.ErrorStatus, "application/json", apiErr.RawBody)
+				return
+			}
+			problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+			c.JSON(int(problemDetails.Status), problemDetails)
+			return
+		}
+	}
+
+	// the UDR holds the modification now; a PDU session deregistered meanwhile only has
+	// no cached registration left to update
+	if !p.Context().UpdateSmfRegContext(ueID, pduID32, request) {
+		logger.UecmLog.Warnf("[UpdateSmfRegistration] PDU session [%s] was deregistered during the update",
+			pduSessionID)
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetSmfRegistrationProcedure returns the SMF registrations of the UE stored in the UDR,
+// restricted to singleNssai and dnn when given
+func (p *Processor) GetSmfRegistrationProcedure(c *gin.Context, ueID string, singleNssai *models.Snssai,
+	dnn string, supportedFeatures string,
+) {
+	ctx, pd, err := p.Context().GetTokenCtx(models.Nrf_NFMgmt_ServiceName_NUDR_DR, models.Nrf_NFMgmt_NFType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+	var querySmfRegListRequest Nudr_DataRepository.QuerySmfRegListRequest
+	querySmfRegListRequest.UeId = &ueID
+	querySmfRegListRequest.SupportedFeatures = &supportedFeatures
+
+	clientAPI, err := p.Consumer().CreateUDMClientToUDR(ueID)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	smfRegList, err := clientAPI.SMFRegistrationsCollectionApi.QuerySmfRegList(ctx, &querySmfRegListRequest)
+	if err != nil {
+		apiError, ok := err.(openapi.GenericOpenAPIError)
+		if ok {
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(apiError.ErrorStatus))
+			c.Data(apiError.ErrorStatus, "application/json", apiError.RawBody)
+			return
+		}
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	var smfRegistrationInfo models.Udm_UECM_SmfRegistrationInfo
+	if smfRegList != nil {
+		for i := range smfRegList.Udm_UECM_SmfRegistration {
+			if udm_context.SmfRegistrationMatches(&smfRegList.Udm_UECM_SmfRegistration[i], singleNssai, dnn) {
+				smfRegistrationInfo.SmfRegistrationList = append(smfRegistrationInfo.SmfRegistrationList,
+					smfRegList.Udm_UECM_SmfRegistration[i])
+			}
+		}
+	}
+	if len(smfRegistrationInfo.SmfRegistrationList) == 0 {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusNotFound,
+			Cause:  "CONTEXT_NOT_FOUND",
+			Detail: "No SMF registration of the UE matches the query",
+		}
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+	c.JSON(http.StatusOK, smfRegistrationInfo)
+}
+
+// RetrieveSmfRegistrationProcedure returns the SMF registration of the PDU session of the UE,
+// CONTEXT_NOT_FOUND if it is not for singleNssai and dnn when given
+func (p *Processor) RetrieveSmfRegistrationProcedure(c *gin.Context, ueID string, pduSessionID string,
+	singleNssai *models.Snssai, dnn string, supportedFeatures string,
+) {
+	ctx, pd, err := p.Context().GetTokenCtx(models.Nrf_NFMgmt_ServiceName_NUDR_DR, models.Nrf_NFMgmt_NFType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+	pduID64, err := strconv.ParseInt(pduSessionID, 10, 32)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+	pduID32 := int32(pduID64)
+
+	var querySmfRegistrationRequest Nudr_DataRepository.QuerySmfRegistrationRequest
+	querySmfRegistrationRequest.UeId = &ueID
+	querySmfRegistrationRequest.PduSessionId = &pduID32
+	querySmfRegistrationRequest.SupportedFeatures = &supportedFeatures
+
+	clientAPI, err := p.Consumer().CreateUDMClientToUDR(ueID)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	smfRegistration, err := clientAPI.SMFRegistrationDocumentApi.
+		QuerySmfRegistration(ctx, &querySmfRegistrationRequest)
+	if err != nil {
+		apiError, ok := err.(openapi.GenericOpenAPIError)
+		if ok {
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(apiError.ErrorStatus))
+			c.Data(apiError.ErrorStatus, "application/json", apiError.RawBody)
+			return
+		}
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	if smfRegistration == nil || smfRegistration.Udm_UECM_SmfRegistration == nil ||
+		!udm_context.SmfRegistrationMatches(smfRegistration.Udm_UECM_SmfRegistration, singleNssai, dnn) {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusNotFound,
+			Cause:  "CONTEXT_NOT_FOUND",
+			Detail: "No SMF registration of the PDU session matches the query",
+		}
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+	c.JSON(http.StatusOK, smfRegistration.Udm_UECM_SmfRegistration)
+}
+
+func (p *Processor) RegistrationSmsf3gppAccessProcedure(c *gin.Context,
+	registerRequest models.Udm_UECM_SmsfRegistration,
+	ueID string,
+) {
+	ctx, pd, err := p.Context().GetTokenCtx(models.Nrf_NFMgmt_ServiceName_NUDR_DR, models.Nrf_NFMgmt_NFType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+	oldSmsf3GppAccessRegContext := p.Context().GetSmsf3gppRegContext(ueID)
+
+	clientAPI, err := p.Consumer().CreateUDMClientToUDR(ueID)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	var createSmsfContext3gppRequest Nudr_DataRepository.CreateSmsfContext3gppRequest
+	createSmsfContext3gppRequest.UeId = &ueID
+	createSmsfContext3gppRequest.RequestBody = &registerRequest
+	_, err = clientAPI.SMSF3GPPRegistrationDocumentApi.CreateSmsfContext3gpp(ctx, &createSmsfContext3gppRequest)
+	if err != nil {
+		apiError, ok := err.(openapi.GenericOpenAPIError)
+		if ok {
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(apiError.ErrorStatus))
+			c.Data(apiError.ErrorStatus, "application/json", apiError.RawBody)
+			return
+		}
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	p.Context().CreateSmsf3gppRegContext(ueID, registerRequest)
+
+	// the registration of a new SMSF replaces the one of the old SMSF for the same access,
+	// Nudm_UECM has no deregistration notification towards SMSFs
+	if oldSmsf3GppAccessRegContext != nil {
+		if oldSmsf3GppAccessRegContext.SmsfInstanceId != registerRequest.SmsfInstanceId {
+			logger.UecmLog.Infof("RegistrationSmsf3gppAccess: SMSF [%s] replaces SMSF [%s]",
+				registerRequest.SmsfInstanceId, oldSmsf3GppAccessRegContext.SmsfInstanceId)
+		}
+		c.JSON(http.StatusOK, registerRequest)
+	} else {
+		udmUe, _ := p.Context().UdmUeFindBySupi(ueID)
+		c.Header("Location", udmUe.GetLocationURI(udm_context.LocationUriSmsf3GppAccessRegistration))
+		c.JSON(http.StatusCreated, registerRequest)
+	}
+}
+
+func (p *Processor) RegistrationSmsfNon3gppAccessProcedure(c *gin.Context,
+	registerRequest models.Udm_UECM_SmsfRegistration,
+	ueID string,
+) {
+	ctx, pd, err := p.Context().GetTokenCtx(models.Nrf_NFMgmt_ServiceName_NUDR_DR, models.Nrf_NFMgmt_NFType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+	oldSmsfNon3GppAccessRegContext := p.Context().GetSmsfNon3gppRegContext(ueID)
+
+	clientAPI, err := p.Consumer().CreateUDMClientToUDR(ueID)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	var createSmsfContextNon3gppRequest Nudr_DataRepository.CreateSmsfContextNon3gppRequest
+	createSmsfContextNon3gppRequest.UeId = &ueID
+	createSmsfContextNon3gppRequest.RequestBody = &registerRequest
+	_, err = clientAPI.SMSFNon3GPPRegistrationDocumentApi.CreateSmsfContextNon3gpp(ctx,
+		&createSmsfContextNon3gppRequest)
+	if err != nil {
+		apiError, ok := err.(openapi.GenericOpenAPIError)
+		if ok {
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(apiError.ErrorStatus))
+			c.Data(apiError.ErrorStatus, "application/json", apiError.RawBody)
+			return
+		}
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	p.Context().CreateSmsfNon3gppRegContext(ueID, registerRequest)
+
+	if oldSmsfNon3GppAccessRegContext != nil {
+		if oldSmsfNon3GppAccessRegContext.SmsfInstanceId != registerRequest.SmsfInstanceId {
+			logger.UecmLog.Infof("RegistrationSmsfNon3gppAccess: SMSF [%s] replaces SMSF [%s]",
+				registerRequest.SmsfInstanceId, oldSmsfNon3GppAccessRegContext.SmsfInstanceId)
+		}
+		c.JSON(http.StatusOK, registerRequest)
+	} else {
+		udmUe, _ := p.Context().UdmUeFindBySupi(ueID)
+		c.Header("Location", udmUe.GetLocationURI(udm_context.LocationUriSmsfNon3GppAccessRegistration))
+		c.JSON(http.StatusCreated, registerRequest)
+	}
+}
+
+func (p *Processor) GetSmsf3gppAccessProcedure(c *gin.Context, ueID string, supportedFeatures string) {
+	ctx, pd, err := p.Context().GetTokenCtx(models.Nrf_NFMgmt_ServiceName_NUDR_DR, models.Nrf_NFMgmt_NFType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+	var querySmsfContext3gppRequest Nudr_DataRepository.QuerySmsfContext3gppRequest
+	querySmsfContext3gppRequest.UeId = &ueID
+	querySmsfContext3gppRequest.SupportedFeatures = &supportedFeatures
+
+	clientAPI, err := p.Consumer().CreateUDMClientToUDR(ueID)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	smsf3GppAccessRegistration, err := clientAPI.SMSF3GPPRegistrationDocumentApi.
+		QuerySmsfContext3gpp(ctx, &querySmsfContext3gppRequest)
+	if err != nil {
+		apiError, ok := err.(openapi.GenericOpenAPIError)
+		if ok {
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(apiError.ErrorStatus))
+			c.Data(apiError.ErrorStatus, "application/json", apiError.RawBody)
+			return
+		}
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	if smsf3GppAccessRegistration == nil || smsf3GppAccessRegistration.Udm_UECM_SmsfRegistration == nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure("UDR returned an empty 3GPP SMSF registration")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+	c.JSON(http.StatusOK, smsf3GppAccessRegistration.Udm_UECM_SmsfRegistration)
+}
+
+func (p *Processor) GetSmsfNon3gppAccessProcedure(c *gin.Context, ueID string, supportedFeatures string) {
+	ctx, pd, err := p.Context().GetTokenCtx(models.Nrf_NFMgmt_ServiceName_NUDR_DR, models.Nrf_NFMgmt_NFType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+	var querySmsfContextNon3gppRequest Nudr_DataRepository.QuerySmsfContextNon3gppRequest
+	querySmsfContextNon3gppRequest.UeId = &ueID
+	querySmsfContextNon3gppRequest.SupportedFeatures = &supportedFeatures
+
+	clientAPI, err := p.Consumer().CreateUDMClientToUDR(ueID)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	smsfNon3GppAccessRegistration, err := clientAPI.SMSFNon3GPPRegistrationDocumentApi.
+		QuerySmsfContextNon3gpp(ctx, &querySmsfContextNon3gppRequest)
+	if err != nil {
+		apiError, ok := err.(openapi.GenericOpenAPIError)
+		if ok {
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(apiError.ErrorStatus))
+			c.Data(apiError.ErrorStatus, "application/json", apiError.RawBody)
+			return
+		}
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	if smsfNon3GppAccessRegistration == nil || smsfNon3GppAccessRegistration.Udm_UECM_SmsfRegistration == nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure("UDR returned an empty non-3GPP SMSF registration")
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+	c.JSON(http.StatusOK, smsfNon3GppAccessRegistration.Udm_UECM_SmsfRegistration)
+}
+
+func (p *Processor) DeregistrationSmsf3gppAccessProcedure(c *gin.Context, ueID string) {
+	ctx, pd, err := p.Context().GetTokenCtx(models.Nrf_NFMgmt_ServiceName_NUDR_DR, models.Nrf_NFMgmt_NFType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+
+	clientAPI, err := p.Consumer().CreateUDMClientToUDR(ueID)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	var deleteSmsfContext3gppRequest Nudr_DataRepository.DeleteSmsfContext3gppRequest
+	deleteSmsfContext3gppRequest.UeId = &ueID
+	_, err = clientAPI.SMSF3GPPRegistrationDocumentApi.DeleteSmsfContext3gpp(ctx, &deleteSmsfContext3gppRequest)
+	if err != nil {
+		apiError, ok := err.(openapi.GenericOpenAPIError)
+		if ok {
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(apiError.ErrorStatus))
+			c.Data(apiError.ErrorStatus, "application/json", apiError.RawBody)
+			return
+		}
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	if udmUe, ok := p.Context().UdmUeFindBySupi(ueID); ok {
+		udmUe.Smsf3GppAccessRegistration = nil
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (p *Processor) DeregistrationSmsfNon3gppAccessProcedure(c *gin.Context, ueID string) {
+	ctx, pd, err := p.Context().GetTokenCtx(models.Nrf_NFMgmt_ServiceName_NUDR_DR, models.Nrf_NFMgmt_NFType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+
+	clientAPI, err := p.Consumer().CreateUDMClientToUDR(ueID)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	var deleteSmsfContextNon3gppRequest Nudr_DataRepository.DeleteSmsfContextNon3gppRequest
+	deleteSmsfContextNon3gppRequest.UeId = &ueID
+	_, err = clientAPI.SMSFNon3GPPRegistrationDocumentApi.DeleteSmsfContextNon3gpp(ctx,
+		&deleteSmsfContextNon3gppRequest)
+	if err != nil {
+		apiError, ok := err.(openapi.GenericOpenAPIError)
+		if ok {
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(apiError.ErrorStatus))
+			c.Data(apiError.ErrorStatus, "application/json", apiError.RawBody)
+			return
+		}
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	if udmUe, ok := p.Context().UdmUeFindBySupi(ueID); ok {
+		udmUe.SmsfNon3GppAccessRegistration = nil
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// DeregAMFProcedure deregisters the AMF serving the UE over 3GPP access on request of the
+// operator, e.g. after barring or a SIM swap: the AMF is notified with the given reason and,
+// once it accepted, its registration is purged from the UDR and from the UE context
+func (p *Processor) DeregAMFProcedure(c *gin.Context, deregInfo models.Udm_UECM_AmfDeregInfo, ueID string) {
+	ctx, pd, err := p.Context().GetTokenCtx(models.Nrf_NFMgmt_ServiceName_NUDR_DR, models.Nrf_NFMgmt_NFType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+	clientAPI, err := p.Consumer().CreateUDMClientToUDR(ueID)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	amf3GppAccessRegistration := p.Context().GetAmf3gppRegContext(ueID)
+	if amf3GppAccessRegistration == nil {
+		// the AMF may have registered before a restart of the UDM, the UDR holds it then
+		var queryAmfContext3gppRequest Nudr_DataRepository.QueryAmfContext3gppRequest
+		queryAmfContext3gppRequest.UeId = &ueID
+		rsp, errQuery := clientAPI.AMF3GPPAccessRegistrationDocumentApi.
+			QueryAmfContext3gpp(ctx, &queryAmfContext3gppRequest)
+		if errQuery != nil && !udrHasNoData(errQuery) {
+			if apiErr, ok := errQuery.(openapi.GenericOpenAPIError); ok {
+				c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(apiErr.ErrorStatus))
+				c.Data(apiErr.ErrorStatus, "application/json", apiErr.RawBody)
+				return
+			}
+			problemDetails := openapi.ProblemDetailsSystemFailure(errQuery.Error())
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+			c.JSON(int(problemDetails.Status), problemDetails)
+			return
+		}
+		if errQuery == nil && rsp != nil && rsp.Udm_UECM_Amf3GppAccessRegistration != nil &&
+			!rsp.Udm_UECM_Amf3GppAccessRegistration.PurgeFlag {
+			amf3GppAccessRegistration = rsp.Udm_UECM_Amf3GppAccessRegistration
+		}
+	}
+	if amf3GppAccessRegistration == nil {
+		logger.UecmLog.Errorln("[DeregAMF] Empty Amf3gppRegContext")
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusNotFound,
+			Cause:  "CONTEXT_NOT_FOUND",
+		}
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	deregistData := models.Udm_UECM_DeregistrationData{
+		DeregReason: deregInfo.DeregReason,
+		AccessType:  models.AccessType_3_GPP_ACCESS,
+	}
+	logger.UecmLog.Infof("Send DeregNotify to AMF GUAMI=%v", amf3GppAccessRegistration.Guami)
+	if problemDetails := p.SendOnDeregistrationNotification(ueID, amf3GppAccessRegistration.DeregCallbackUri,
+		deregistData); problemDetails != nil {
+		logger.UecmLog.Errorf("DeregAMF: send DeregNotify fail %v", problemDetails)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	// the UDR has no deletion of the AMF registration document, it is purged as on a
+	// deregistration by the AMF itself
+	var amfContext3gppRequest Nudr_DataRepository.AmfContext3gppRequest
+	amfContext3gppRequest.UeId = &ueID
+	amfContext3gppRequest.RequestBody = []models.PatchItem{
+		{
+			Op:    models.PatchOperation_ADD,
+			Path:  "/purgeFlag",
+			Value: true,
+		},
+	}
+	_, err = clientAPI.AMF3GPPAccessRegistrationDocumentApi.AmfContext3gpp(ctx, &amfContext3gppRequest)
+	if err != nil {
+		if apiErr, ok := err.(openapi.GenericOpenAPIError); ok {
+			if amfContext3gppErr, ok2 := apiErr.Model().(Nudr_DataRepository.AmfContext3gppError); ok2 &&
+				amfContext3gppErr.ProblemDetails != nil {
+				problem := amfContext3gppErr.ProblemDetails
+				c.Set(sbi.IN_PB_DETAILS_CTX_STR, problem.Cause)
+				c.JSON(int(problem.Status), problem)
+				return
+			}
+		}
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	if udmUe, ok := p.Context().UdmUeFindBySupi(ueID); ok {
+		udmUe.Amf3GppAccessRegistration = nil
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetRegistrationsProcedure returns the registrations of the NFs serving the UE for datasetNames,
+// the SMF registrations restricted to singleNssai and dnn when given. A dataset the UDR has no
+// data for is taken from the UE context.
+func (p *Processor) GetRegistrationsProcedure(c *gin.Context, ueID string,
+	datasetNames []models.Udm_UECM_RegistrationDataSetName, singleNssai *models.Snssai, dnn string,
+	supportedFeatures string,
+) {
+	ctx, pd, err := p.Context().GetTokenCtx(models.Nrf_NFMgmt_ServiceName_NUDR_DR, models.Nrf_NFMgmt_NFType_UDR)
+	if err != nil {
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, pd.Cause)
+		c.JSON(int(pd.Status), pd)
+		return
+	}
+
+	clientAPI, err := p.Consumer().CreateUDMClientToUDR(ueID)
+	if err != nil {
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	var registrationDataSets models.Udm_UECM_RegistrationDataSets
+datasets:
+	for _, datasetName := range datasetNames {
+		switch datasetName {
+		case models.Udm_UECM_RegistrationDataSetName_AMF_3_GPP:
+			var queryAmfContext3gppRequest Nudr_DataRepository.QueryAmfContext3gppRequest
+			queryAmfContext3gppRequest.UeId = &ueID
+			queryAmfContext3gppRequest.SupportedFeatures = &supportedFeatures
+			rsp, errQuery := clientAPI.AMF3GPPAccessRegistrationDocumentApi.
+				QueryAmfContext3gpp(ctx, &queryAmfContext3gppRequest)
+			if errQuery != nil && !udrHasNoData(errQuery) {
+				err = errQuery
+				break datasets
+			}
+			if errQuery == nil && rsp != nil && rsp.Udm_UECM_Amf3GppAccessRegistration != nil {
+				registrationDataSets.Amf3Gpp = rsp.Udm_UECM_Amf3GppAccessRegistration
+			} else {
+				registrationDataSets.Amf3Gpp = p.Context().GetAmf3gppRegContext(ueID)
+			}
+		case models.Udm_UECM_RegistrationDataSetName_AMF_NON_3_GPP:
+			var queryAmfContextNon3gppRequest Nudr_DataRepository.QueryAmfContextNon3gppRequest
+			queryAmfContextNon3gppRequest.UeId = &ueID
+			queryAmfContextNon3gppRequest.SupportedFeatures = &supportedFeatures
+			rsp, errQuery := clientAPI.AMFNon3GPPAccessRegistrationDocumentApi.
+				QueryAmfContextNon3gpp(ctx, &queryAmfContextNon3gppRequest)
+			if errQuery != nil && !udrHasNoData(errQuery) {
+				err = errQuery
+				break datasets
+			}
+			if errQuery == nil && rsp != nil && rsp.Udm_UECM_AmfNon3GppAccessRegistration != nil {
+				registrationDataSets.AmfNon3Gpp = rsp.Udm_UECM_AmfNon3GppAccessRegistration
+			} else {
+				registrationDataSets.AmfNon3Gpp = p.Context().GetAmfNon3gppRegContext(ueID)
+			}
+		case models.Udm_UECM_RegistrationDataSetName_SMF_PDU_SESSIONS:
+			var querySmfRegListRequest Nudr_DataRepository.QuerySmfRegListRequest
+			querySmfRegListRequest.UeId = &ueID
+			querySmfRegListRequest.SupportedFeatures = &supportedFeatures
+			rsp, errQuery := clientAPI.SMFRegistrationsCollectionApi.QuerySmfRegList(ctx, &querySmfRegListRequest)
+			if errQuery != nil && !udrHasNoData(errQuery) {
+				err = errQuery
+				break datasets
+			}
+			var smfRegistrations []models.Udm_UECM_SmfRegistration
+			if errQuery == nil && rsp != nil && len(rsp.Udm_UECM_SmfRegistration) > 0 {
+				for i := range rsp.Udm_UECM_SmfRegistration {
+					if udm_context.SmfRegistrationMatches(&rsp.Udm_UECM_SmfRegistration[i], singleNssai, dnn) {
+						smfRegistrations = append(smfRegistrations, rsp.Udm_UECM_SmfRegistration[i])
+					}
+				}
+			} else if udmUe, ok := p.Context().UdmUeFindBySupi(ueID); ok {
+				smfRegistrations = udmUe.GetSmfRegistrations(singleNssai, dnn)
+			}
+			if len(smfRegistrations) > 0 {
+				registrationDataSets.SmfRegistration = &models.Udm_UECM_SmfRegistrationInfo{
+					SmfRegistrationList: smfRegistrations,
+				}
+			}
+		case models.Udm_UECM_RegistrationDataSetName_SMSF_3_GPP:
+			var querySmsfContext3gppRequest Nudr_DataRepository.QuerySmsfContext3gppRequest
+			querySmsfContext3gppRequest.UeId = &ueID
+			querySmsfContext3gppRequest.SupportedFeatures = &supportedFeatures
+			rsp, errQuery := clientAPI.SMSF3GPPRegistrationDocumentApi.
+				QuerySmsfContext3gpp(ctx, &querySmsfContext3gppRequest)
+			if errQuery != nil && !udrHasNoData(errQuery) {
+				err = errQuery
+				break datasets
+			}
+			if errQuery == nil && rsp != nil && rsp.Udm_UECM_SmsfRegistration != nil {
+				registrationDataSets.Smsf3Gpp = rsp.Udm_UECM_SmsfRegistration
+			} else {
+				registrationDataSets.Smsf3Gpp = p.Context().GetSmsf3gppRegContext(ueID)
+			}
+		case models.Udm_UECM_RegistrationDataSetName_SMSF_NON_3_GPP:
+			var querySmsfContextNon3gppRequest Nudr_DataRepository.QuerySmsfContextNon3gppRequest
+			querySmsfContextNon3gppRequest.UeId = &ueID
+			querySmsfContextNon3gppRequest.SupportedFeatures = &supportedFeatures
+			rsp, errQuery := clientAPI.SMSFNon3GPPRegistrationDocumentApi.
+				QuerySmsfContextNon3gpp(ctx, &querySmsfContextNon3gppRequest)
+			if errQuery != nil && !udrHasNoData(errQuery) {
+				err = errQuery
+				break datasets
+			}
+			if errQuery == nil && rsp != nil && rsp.Udm_UECM_SmsfRegistration != nil {
+				registrationDataSets.SmsfNon3Gpp = rsp.Udm_UECM_SmsfRegistration
+			} else {
+				registrationDataSets.SmsfNon3Gpp = p.Context().GetSmsfNon3gppRegContext(ueID)
+			}
+		case models.Udm_UECM_RegistrationDataSetName_IP_SM_GW:
+			// IP-SM-GW registrations are only kept in the UDR
+			var queryIpSmGwContextRequest Nudr_DataRepository.QueryIpSmGwContextRequest
+			queryIpSmGwContextRequest.UeId = &ueID
+			queryIpSmGwContextRequest.SupportedFeatures = &supportedFeatures
+			rsp, errQuery := clientAPI.IPSMGWRegistrationDocumentApi.
+				QueryIpSmGwContext(ctx, &queryIpSmGwContextRequest)
+			if errQuery != nil && !udrHasNoData(errQuery) {
+				err = errQuery
+				break datasets
+			}
+			if errQuery == nil && rsp != nil {
+				registrationDataSets.IpSmGw = rsp.Udm_UECM_IpSmGwRegistration
+			}
+		default:
+			// the UDR has no NWDAF registrations and the UDM does not serve them
+			logger.UecmLog.Warnf("GetRegistrations: dataset [%s] is not supported", datasetName)
+		}
+	}
+	if err != nil {
+		apiError, ok := err.(openapi.GenericOpenAPIError)
+		if ok {
+			c.Set(sbi.IN_PB_DETAILS_CTX_STR, http.StatusText(apiError.ErrorStatus))
+			c.Data(apiError.ErrorStatus, "application/json", apiError.RawBody)
+			return
+		}
+		problemDetails := openapi.ProblemDetailsSystemFailure(err.Error())
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	if registrationDataSets == (models.Udm_UECM_RegistrationDataSets{}) {
+		problemDetails := &models.ProblemDetails{
+			Status: http.StatusNotFound,
+			Cause:  "CONTEXT_NOT_FOUND",
+			Detail: "No registration of the UE matches the query",
+		}
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+	c.JSON(http.StatusOK, registrationDataSets)
+}
+
+// udrHasNoData tells whether err is the UDR answering that it has no data for the query
+func udrHasNoData(err error) bool {
+	apiError, ok := err.(openapi.GenericOpenAPIError)
+	return ok && apiError.ErrorStatus == http.StatusNotFound
+}
@@ -0,0 +1,545 @@
+package processor
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/h2non/gock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/free5gc/openapi"
+	"github.com/free5gc/openapi/models"
+	udm_context "github.com/free5gc/udm/internal/context"
+)
+
+func TestSmsf3gppAccessRegistrationLifecycle(t *testing.T) {
+	defer gock.Off()
+	openapi.InterceptInnerHttp2Client(t, false)
+
+	const supi = "imsi-208930000000021"
+	const smsfPath = "/nudr-dr/v2/subscription-data/" + supi + "/context-data/smsf-3gpp-access"
+
+	udmContext := &udm_context.UDMContext{NrfUri: "http://127.0.0.10:8000", NfId: "1"}
+	testProcessor := newTestProcessor(t, udmContext, supi)
+
+	plmnId := &models.PlmnId{Mcc: "208", Mnc: "93"}
+	firstSmsf := models.Udm_UECM_SmsfRegistration{
+		SmsfInstanceId: "5f0a2b1c-6a0e-4d7e-9d3b-8c2f3e1a0001",
+		PlmnId:         plmnId,
+	}
+	secondSmsf := models.Udm_UECM_SmsfRegistration{
+		SmsfInstanceId: "5f0a2b1c-6a0e-4d7e-9d3b-8c2f3e1a0002",
+		PlmnId:         plmnId,
+	}
+
+	// initial registration
+	gock.New("http://127.0.0.4:8000").Put(smsfPath).Reply(http.StatusNoContent)
+	httpRecorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(httpRecorder)
+	testProcessor.RegistrationSmsf3gppAccessProcedure(c, firstSmsf, supi)
+	require.Equal(t, http.StatusCreated, httpRecorder.Code)
+	require.Contains(t, httpRecorder.Header().Get("Location"), "/"+supi+"/registrations/smsf-3gpp-access")
+	require.Equal(t, firstSmsf.SmsfInstanceId, udmContext.GetSmsf3gppRegContext(supi).SmsfInstanceId)
+
+	// re-registration from another SMSF replaces the registration
+	gock.New("http://127.0.0.4:8000").Put(smsfPath).Reply(http.StatusNoContent)
+	httpRecorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(httpRecorder)
+	testProcessor.RegistrationSmsf3gppAccessProcedure(c, secondSmsf, supi)
+	require.Equal(t, http.StatusOK, httpRecorder.Code)
+	require.Empty(t, httpRecorder.Header().Get("Location"))
+	require.Equal(t, secondSmsf.SmsfInstanceId, udmContext.GetSmsf3gppRegContext(supi).SmsfInstanceId)
+
+	gock.New("http://127.0.0.4:8000").Get(smsfPath).
+		Reply(http.StatusOK).
+		AddHeader("Content-Type", "application/json").
+		JSON(secondSmsf)
+	httpRecorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(httpRecorder)
+	testProcessor.GetSmsf3gppAccessProcedure(c, supi, "")
+	require.Equal(t, http.StatusOK, httpRecorder.Code)
+	var registration models.Udm_UECM_SmsfRegistration
+	require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &registration))
+	require.Equal(t, secondSmsf.SmsfInstanceId, registration.SmsfInstanceId)
+
+	gock.New("http://127.0.0.4:8000").Delete(smsfPath).Reply(http.StatusNoContent)
+	httpRecorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(httpRecorder)
+	testProcessor.DeregistrationSmsf3gppAccessProcedure(c, supi)
+	c.Writer.WriteHeaderNow()
+	require.Equal(t, http.StatusNoContent, httpRecorder.Code)
+	require.Nil(t, udmContext.GetSmsf3gppRegContext(supi))
+	require.True(t, gock.IsDone())
+}
+
+func TestSmsfNon3gppAccessRegistration_UDRError(t *testing.T) {
+	defer gock.Off()
+	openapi.InterceptInnerHttp2Client(t, false)
+
+	const supi = "imsi-208930000000022"
+
+	udmContext := &udm_context.UDMContext{NrfUri: "http://127.0.0.10:8000", NfId: "1"}
+	testProcessor := newTestProcessor(t, udmContext, supi)
+
+	gock.New("http://127.0.0.4:8000").
+		Put("/nudr-dr/v2/subscription-data/"+supi+"/context-data/smsf-non-3gpp-access").
+		Reply(http.StatusInternalServerError).
+		AddHeader("Content-Type", "application/json").
+		BodyString(`{"status":500,"cause":"SYSTEM_FAILURE"}`)
+
+	httpRecorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(httpRecorder)
+	testProcessor.RegistrationSmsfNon3gppAccessProcedure(c, models.Udm_UECM_SmsfRegistration{
+		SmsfInstanceId: "5f0a2b1c-6a0e-4d7e-9d3b-8c2f3e1a0003",
+		PlmnId:         &models.PlmnId{Mcc: "208", Mnc: "93"},
+	}, supi)
+
+	require.Equal(t, http.StatusInternalServerError, httpRecorder.Code)
+	// a registration the UDR did not store is not kept either
+	require.Nil(t, udmContext.GetSmsfNon3gppRegContext(supi))
+}
+
+func TestDeregAMFProcedure(t *testing.T) {
+	const supi = "imsi-208930000000023"
+	const deregCallbackUri = "http://127.0.0.18:8000/namf-callback/v1/" + supi + "/dereg-notify"
+
+	testCases := []struct {
+		name             string
+		registered       bool
+		udrQueryStatus   int
+		amfStatus        int
+		expectStatus     int
+		expectCause      string
+		expectRegistered bool
+	}{
+		{
+			name:         "AMF deregistered",
+			registered:   true,
+			amfStatus:    http.StatusNoContent,
+			expectStatus: http.StatusNoContent,
+		},
+		{
+			name:             "AMF rejects the notification",
+			registered:       true,
+			amfStatus:        http.StatusForbidden,
+			expectStatus:     http.StatusForbidden,
+			expectCause:      "NOTIFICATION_REJECTED",
+			expectRegistered: true,
+		},
+		{
+			name:           "AMF registration only known to the UDR",
+			udrQueryStatus: http.StatusOK,
+			amfStatus:      http.StatusNoContent,
+			expectStatus:   http.StatusNoContent,
+		},
+		{
+			name:           "no AMF registration",
+			udrQueryStatus: http.StatusNotFound,
+			expectStatus:   http.StatusNotFound,
+			expectCause:    "CONTEXT_NOT_FOUND",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer gock.Off()
+			openapi.InterceptInnerHttp2Client(t, false)
+
+			udmContext := &udm_context.UDMContext{NrfUri: "http://127.0.0.10:8000", NfId: "1"}
+			testProcessor := newTestProcessor(t, udmContext, supi)
+			if tc.registered {
+				udmContext.CreateAmf3gppRegContext(supi, models.Udm_UECM_Amf3GppAccessRegistration{
+					AmfInstanceId:    "5f0a2b1c-6a0e-4d7e-9d3b-8c2f3e1a0010",
+					DeregCallbackUri: deregCallbackUri,
+				})
+			}
+
+			switch tc.udrQueryStatus {
+			case http.StatusOK:
+				gock.New("http://127.0.0.4:8000").
+					Get("/nudr-dr/v2/subscription-data/" + supi + "/context-data/amf-3gpp-access").
+					Reply(http.StatusOK).
+					JSON(models.Udm_UECM_Amf3GppAccessRegistration{
+						AmfInstanceId:    "5f0a2b1c-6a0e-4d7e-9d3b-8c2f3e1a0010",
+						DeregCallbackUri: deregCallbackUri,
+					})
+			case http.StatusNotFound:
+				gock.New("http://127.0.0.4:8000").
+					Get("/nudr-dr/v2/subscription-data/"+supi+"/context-data/amf-3gpp-access").
+					Reply(http.StatusNotFound).
+					AddHeader("Content-Type", "application/problem+json").
+					JSON(models.ProblemDetails{Status: http.StatusNotFound, Cause: "DATA_NOT_FOUND"})
+			}
+			if tc.amfStatus == http.StatusNoContent {
+				gock.New("http://127.0.0.18:8000").
+					Post("/namf-callback/v1/" + supi + "/dereg-notify").
+					Reply(http.StatusNoContent)
+				gock.New("http://127.0.0.4:8000").
+					Patch("/nudr-dr/v2/subscription-data/" + supi + "/context-data/amf-3gpp-access").
+					Reply(http.StatusNoContent)
+			} else if tc.amfStatus != 0 {
+				gock.New("http://127.0.0.18:8000").
+					Post("/namf-callback/v1/"+supi+"/dereg-notify").
+					Reply(tc.amfStatus).
+					AddHeader("Content-Type", "application/problem+json").
+					JSON(models.ProblemDetails{Status: int32(tc.amfStatus), Cause: tc.expectCause})
+			}
+
+			httpRecorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(httpRecorder)
+			testProcessor.DeregAMFProcedure(c, models.Udm_UECM_AmfDeregInfo{
+				DeregReason: models.Udm_UECM_DeregistrationReason_SUBSCRIPTION_WITHDRAWN,
+			}, supi)
+			c.Writer.WriteHeaderNow()
+
+			require.Equal(t, tc.expectStatus, httpRecorder.Code)
+			if tc.expectCause != "" {
+				var problemDetails models.ProblemDetails
+				require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &problemDetails))
+				require.Equal(t, tc.expectCause, problemDetails.Cause)
+			}
+			require.Equal(t, tc.expectRegistered, udmContext.GetAmf3gppRegContext(supi) != nil)
+			require.True(t, gock.IsDone())
+		})
+	}
+}
+
+// the re-registration with a new AMF succeeds whatever becomes of the deregistration
+// notification sent to the old AMF
+func TestRegistrationAmf3gppAccessOldAmfNotificationFails(t *testing.T) {
+	const supi = "imsi-208930000000024"
+	guami := func(amfId string) *models.Guami {
+		return &models.Guami{PlmnId: &models.PlmnIdNid{Mcc: "208", Mnc: "93"}, AmfId: amfId}
+	}
+
+	testCases := []struct {
+		name        string
+		notifyReply func(*gock.Response)
+	}{
+		{
+			name: "old AMF answers with an unmodelled status",
+			notifyReply: func(res *gock.Response) {
+				res.Status(http.StatusInternalServerError)
+			},
+		},
+		{
+			name: "old AMF unreachable",
+			notifyReply: func(res *gock.Response) {
+				res.SetError(errors.New("connection refused"))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer gock.Off()
+			openapi.InterceptInnerHttp2Client(t, false)
+
+			udmContext := &udm_context.UDMContext{NrfUri: "http://127.0.0.10:8000", NfId: "1"}
+			testProcessor := newTestProcessor(t, udmContext, supi)
+			udmContext.CreateAmf3gppRegContext(supi, models.Udm_UECM_Amf3GppAccessRegistration{
+				AmfInstanceId:    "5f0a2b1c-6a0e-4d7e-9d3b-8c2f3e1a0010",
+				DeregCallbackUri: "http://127.0.0.18:8000/namf-callback/v1/" + supi + "/dereg-notify",
+				Guami:            guami("cafe00"),
+			})
+
+			gock.New("http://127.0.0.4:8000").
+				Put("/nudr-dr/v2/subscription-data/" + supi + "/context-data/amf-3gpp-access").
+				Reply(http.StatusNoContent)
+			tc.notifyReply(gock.New("http://127.0.0.18:8000").
+				Post("/namf-callback/v1/" + supi + "/dereg-notify").
+				Reply(http.StatusNoContent))
+
+			registration := models.Udm_UECM_Amf3GppAccessRegistration{
+				AmfInstanceId:    "5f0a2b1c-6a0e-4d7e-9d3b-8c2f3e1a0011",
+				DeregCallbackUri: "http://127.0.0.19:8000/namf-callback/v1/" + supi + "/dereg-notify",
+				Guami:            guami("cafe01"),
+			}
+			httpRecorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(httpRecorder)
+			testProcessor.RegistrationAmf3gppAccessProcedure(c, registration, supi)
+
+			require.Equal(t, http.StatusOK, httpRecorder.Code)
+			require.Equal(t, registration.AmfInstanceId, udmContext.GetAmf3gppRegContext(supi).AmfInstanceId)
+			// the notification is sent in the background
+			require.Eventually(t, gock.IsDone, time.Second, 10*time.Millisecond)
+		})
+	}
+}
+
+func TestSmfRegistrationsPerPduSession(t *testing.T) {
+	defer gock.Off()
+	openapi.InterceptInnerHttp2Client(t, false)
+
+	const supi = "imsi-208930000000024"
+	const smfPath = "/nudr-dr/v2/subscription-data/" + supi + "/context-data/smf-registrations"
+
+	udmContext := &udm_context.UDMContext{NrfUri: "http://127.0.0.10:8000", NfId: "1"}
+	testProcessor := newTestProcessor(t, udmContext, supi)
+
+	internet := models.Udm_UECM_SmfRegistration{
+		SmfInstanceId: "5f0a2b1c-6a0e-4d7e-9d3b-8c2f3e1a0020",
+		PduSessionId:  1,
+		SingleNssai:   &models.Snssai{Sst: 1, Sd: "010203"},
+		Dnn:           "internet",
+		PlmnId:        &models.PlmnId{Mcc: "208", Mnc: "93"},
+	}
+	ims := models.Udm_UECM_SmfRegistration{
+		SmfInstanceId: "5f0a2b1c-6a0e-4d7e-9d3b-8c2f3e1a0020",
+		PduSessionId:  2,
+		SingleNssai:   &models.Snssai{Sst: 1, Sd: "112233"},
+		Dnn:           "ims",
+		PlmnId:        &models.PlmnId{Mcc: "208", Mnc: "93"},
+	}
+
+	// both PDU sessions are tracked, and only a re-registration finds its context
+	for _, step := range []struct {
+		registration models.Udm_UECM_SmfRegistration
+		pduSessionID string
+		expectStatus int
+	}{
+		{internet, "1", http.StatusCreated},
+		{ims, "2", http.StatusCreated},
+		{internet, "1", http.StatusNoContent},
+	} {
+		gock.New("http://127.0.0.4:8000").Put(smfPath + "/" + step.pduSessionID).Reply(http.StatusNoContent)
+		httpRecorder := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(httpRecorder)
+		testProcessor.RegistrationSmfRegistrationsProcedure(c, &step.registration, supi, step.pduSessionID)
+		c.Writer.WriteHeaderNow()
+		require.Equal(t, step.expectStatus, httpRecorder.Code)
+		if step.expectStatus == http.StatusCreated {
+			require.True(t, strings.HasSuffix(httpRecorder.Header().Get("Location"),
+				"/"+supi+"/registrations/smf-registrations/"+step.pduSessionID))
+		}
+	}
+	udmUe, ok := udmContext.UdmUeFindBySupi(supi)
+	require.True(t, ok)
+	require.Len(t, udmUe.GetSmfRegistrations(nil, ""), 2)
+	require.Equal(t, []models.Udm_UECM_SmfRegistration{ims}, udmUe.GetSmfRegistrations(nil, "IMS"))
+
+	gock.New("http://127.0.0.4:8000").Get(smfPath+"$").
+		Reply(http.StatusOK).
+		AddHeader("Content-Type", "application/json").
+		JSON([]models.Udm_UECM_SmfRegistration{internet, ims})
+	httpRecorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(httpRecorder)
+	testProcessor.GetSmfRegistrationProcedure(c, supi, &models.Snssai{Sst: 1, Sd: "010203"}, "", "")
+	require.Equal(t, http.StatusOK, httpRecorder.Code)
+	var smfRegistrationInfo models.Udm_UECM_SmfRegistrationInfo
+	require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &smfRegistrationInfo))
+	require.Len(t, smfRegistrationInfo.SmfRegistrationList, 1)
+	require.Equal(t, int32(1), smfRegistrationInfo.SmfRegistrationList[0].PduSessionId)
+
+	gock.New("http://127.0.0.4:8000").Get(smfPath+"/2").
+		Reply(http.StatusOK).
+		AddHeader("Content-Type", "application/json").
+		JSON(ims)
+	httpRecorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(httpRecorder)
+	testProcessor.RetrieveSmfRegistrationProcedure(c, supi, "2", nil, "internet", "")
+	require.Equal(t, http.StatusNotFound, httpRecorder.Code)
+
+	gock.New("http://127.0.0.4:8000").Delete(smfPath + "/1").Reply(http.StatusNoContent)
+	httpRecorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(httpRecorder)
+	testProcessor.DeregistrationSmfRegistrationsProcedure(c, supi, "1")
+	c.Writer.WriteHeaderNow()
+	require.Equal(t, http.StatusNoContent, httpRecorder.Code)
+	require.Nil(t, udmContext.GetSmfRegContext(supi, 1))
+	require.NotNil(t, udmContext.GetSmfRegContext(supi, 2))
+	require.True(t, gock.IsDone())
+}
+
+func TestUpdateSmfRegistrationProcedure(t *testing.T) {
+	const supi = "imsi-208930000000025"
+	const smfInstanceId = "5f0a2b1c-6a0e-4d7e-9d3b-8c2f3e1a0030"
+
+	testCases := []struct {
+		name           string
+		pduSessionID   string
+		udrQueryStatus int
+		udrStatus      int
+		expectStatus   int
+		expectCause    string
+		expectPgwFqdn  string
+	}{
+		{
+			name:          "PGW-C FQDN changed",
+			pduSessionID:  "5",
+			udrStatus:     http.StatusNoContent,
+			expectStatus:  http.StatusNoContent,
+			expectPgwFqdn: "pgw2.example.org",
+		},
+		{
+			name:          "UDR rejects the update",
+			pduSessionID:  "5",
+			udrStatus:     http.StatusNotFound,
+			expectStatus:  http.StatusNotFound,
+			expectCause:   "DATA_NOT_FOUND",
+			expectPgwFqdn: "pgw1.example.org",
+		},
+		{
+			name:           "registration only known to the UDR",
+			pduSessionID:   "7",
+			udrQueryStatus: http.StatusOK,
+			udrStatus:      http.StatusNoContent,
+			expectStatus:   http.StatusNoContent,
+			expectPgwFqdn:  "pgw2.example.org",
+		},
+		{
+			name:           "unknown PDU session",
+			pduSessionID:   "6",
+			udrQueryStatus: http.StatusNotFound,
+			expectStatus:   http.StatusNotFound,
+			expectCause:    "CONTEXT_NOT_FOUND",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer gock.Off()
+			openapi.InterceptInnerHttp2Client(t, false)
+
+			udmContext := &udm_context.UDMContext{NrfUri: "http://127.0.0.10:8000", NfId: "1"}
+			testProcessor := newTestProcessor(t, udmContext, supi)
+			udmContext.CreateSmfRegContext(supi, 5, models.Udm_UECM_SmfRegistration{
+				SmfInstanceId: smfInstanceId,
+				Dnn:           "internet",
+				PgwFqdn:       "pgw1.example.org",
+			})
+
+			smfRegistrationPath := "/nudr-dr/v2/subscription-data/" + supi +
+				"/context-data/smf-registrations/" + tc.pduSessionID + "$"
+			switch tc.udrQueryStatus {
+			case http.StatusOK:
+				gock.New("http://127.0.0.4:8000").
+					Get(smfRegistrationPath).
+					Reply(http.StatusOK).
+					JSON(models.Udm_UECM_SmfRegistration{
+						SmfInstanceId: smfInstanceId,
+						Dnn:           "internet",
+						PgwFqdn:       "pgw1.example.org",
+					})
+			case http.StatusNotFound:
+				gock.New("http://127.0.0.4:8000").
+					Get(smfRegistrationPath).
+					Reply(http.StatusNotFound).
+					AddHeader("Content-Type", "application/problem+json").
+					JSON(models.ProblemDetails{Status: http.StatusNotFound, Cause: "DATA_NOT_FOUND"})
+			}
+			if tc.udrStatus != 0 {
+				gock.New("http://127.0.0.4:8000").
+					Patch(smfRegistrationPath).
+					Reply(tc.udrStatus).
+					AddHeader("Content-Type", "application/problem+json").
+					JSON(models.ProblemDetails{Status: int32(tc.udrStatus), Cause: tc.expectCause})
+			}
+
+			httpRecorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(httpRecorder)
+			testProcessor.UpdateSmfRegistrationProcedure(c, models.Udm_UECM_SmfRegistrationModification{
+				SmfInstanceId: smfInstanceId,
+				PgwFqdn:       "pgw2.example.org",
+			}, supi, tc.pduSessionID)
+			c.Writer.WriteHeaderNow()
+
+			require.Equal(t, tc.expectStatus, httpRecorder.Code)
+			if tc.expectCause != "" {
+				var problemDetails models.ProblemDetails
+				require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &problemDetails))
+				require.Equal(t, tc.expectCause, problemDetails.Cause)
+			}
+			if tc.expectPgwFqdn != "" {
+				pduSessionID, errAtoi := strconv.Atoi(tc.pduSessionID)
+				require.NoError(t, errAtoi)
+				smfRegistration := udmContext.GetSmfRegContext(supi, int32(pduSessionID))
+				require.NotNil(t, smfRegistration)
+				require.Equal(t, tc.expectPgwFqdn, smfRegistration.PgwFqdn)
+			}
+			require.True(t, gock.IsDone())
+		})
+	}
+}
+
+func TestGetRegistrationsProcedure(t *testing.T) {
+	defer gock.Off()
+	openapi.InterceptInnerHttp2Client(t, false)
+
+	const supi = "imsi-208930000000026"
+	const contextDataPath = "/nudr-dr/v2/subscription-data/" + supi + "/context-data"
+
+	udmContext := &udm_context.UDMContext{NrfUri: "http://127.0.0.10:8000", NfId: "1"}
+	testProcessor := newTestProcessor(t, udmContext, supi)
+
+	amf3Gpp := models.Udm_UECM_Amf3GppAccessRegistration{
+		AmfInstanceId: "5f0a2b1c-6a0e-4d7e-9d3b-8c2f3e1a0040",
+		RatType:       models.RatType_NR,
+	}
+	udmContext.CreateSmfRegContext(supi, 1, models.Udm_UECM_SmfRegistration{
+		SmfInstanceId: "5f0a2b1c-6a0e-4d7e-9d3b-8c2f3e1a0041",
+		SingleNssai:   &models.Snssai{Sst: 1, Sd: "010203"},
+		Dnn:           "internet",
+	})
+	udmContext.CreateSmfRegContext(supi, 2, models.Udm_UECM_SmfRegistration{
+		SmfInstanceId: "5f0a2b1c-6a0e-4d7e-9d3b-8c2f3e1a0041",
+		SingleNssai:   &models.Snssai{Sst: 1, Sd: "112233"},
+		Dnn:           "ims",
+	})
+
+	notFound := models.ProblemDetails{Status: http.StatusNotFound, Cause: "DATA_NOT_FOUND"}
+	// the AMF registration is read from the UDR, the SMF registrations the UDR has no data for
+	// from the UE context, and the SMSF has no registration at all
+	gock.New("http://127.0.0.4:8000").Get(contextDataPath+"/amf-3gpp-access").
+		Reply(http.StatusOK).
+		AddHeader("Content-Type", "application/json").
+		JSON(amf3Gpp)
+	gock.New("http://127.0.0.4:8000").Get(contextDataPath+"/smf-registrations").
+		Reply(http.StatusNotFound).
+		AddHeader("Content-Type", "application/problem+json").
+		JSON(notFound)
+	gock.New("http://127.0.0.4:8000").Get(contextDataPath+"/smsf-3gpp-access").
+		Reply(http.StatusNotFound).
+		AddHeader("Content-Type", "application/problem+json").
+		JSON(notFound)
+
+	httpRecorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(httpRecorder)
+	testProcessor.GetRegistrationsProcedure(c, supi, []models.Udm_UECM_RegistrationDataSetName{
+		models.Udm_UECM_RegistrationDataSetName_AMF_3_GPP,
+		models.Udm_UECM_RegistrationDataSetName_SMF_PDU_SESSIONS,
+		models.Udm_UECM_RegistrationDataSetName_SMSF_3_GPP,
+	}, nil, "ims", "")
+
+	require.Equal(t, http.StatusOK, httpRecorder.Code)
+	var registrationDataSets models.Udm_UECM_RegistrationDataSets
+	require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &registrationDataSets))
+	require.NotNil(t, registrationDataSets.Amf3Gpp)
+	require.Equal(t, amf3Gpp.AmfInstanceId, registrationDataSets.Amf3Gpp.AmfInstanceId)
+	require.NotNil(t, registrationDataSets.SmfRegistration)
+	require.Len(t, registrationDataSets.SmfRegistration.SmfRegistrationList, 1)
+	require.Equal(t, int32(2), registrationDataSets.SmfRegistration.SmfRegistrationList[0].PduSessionId)
+	require.Nil(t, registrationDataSets.Smsf3Gpp)
+	require.True(t, gock.IsDone())
+
+	// no registration anywhere
+	gock.New("http://127.0.0.4:8000").Get(contextDataPath+"/smsf-non-3gpp-access").
+		Reply(http.StatusNotFound).
+		AddHeader("Content-Type", "application/problem+json").
+		JSON(notFound)
+	httpRecorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(httpRecorder)
+	testProcessor.GetRegistrationsProcedure(c, supi, []models.Udm_UECM_RegistrationDataSetName{
+		models.Udm_UECM_RegistrationDataSetName_SMSF_NON_3_GPP,
+		models.Udm_UECM_RegistrationDataSetName_NWDAF,
+	}, nil, "", "")
+	require.Equal(t, http.StatusNotFound, httpRecorder.Code)
+	require.True(t, gock.IsDone())
+}
@@ -0,0 +1,97 @@
+package processor
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/free5gc/openapi/models"
+	"github.com/free5gc/udm/internal/logger"
+	"github.com/free5gc/udm/pkg/suci"
+	"github.com/free5gc/util/metrics/sbi"
+)
+
+const (
+	// the SUCI names a home network public key the UDM does not hold
+	unknownKeyId string = "UNKNOWN_KEY_ID"
+	// the MAC of the scheme output does not verify, the SUCI was altered or concealed with another key
+	deconcealmentMacFailure string = "DECONCEALMENT_MAC_FAILURE"
+	// the protection scheme of the SUCI has no decryptor
+	unsupportedProtectionScheme string = "UNSUPPORTED_PROTECTION_SCHEME"
+	// the routing indicator of the SUCI belongs to no UDM group known to the UDM
+	routingIndicatorNotServed string = "ROUTING_INDICATOR_NOT_SERVED"
+)
+
+// DeconcealProcedure returns the SUPI concealed in a SUCI (Nudm_UEID_Deconceal, TS 29.503 5.8.2.2)
+func (p *Processor) DeconcealProcedure(c *gin.Context, deconcealReqData models.Udm_UEID_DeconcealReqData) {
+	if p.routeSuci(c, deconcealReqData.Suci) {
+		return
+	}
+	supi, err := p.Context().SuciProfiles.ToSupi(deconcealReqData.Suci)
+	if err != nil {
+		problemDetails := deconcealProblemDetails(err)
+		logger.UeidLog.Warnf("Deconceal suci[%s] failed: %+v", deconcealReqData.Suci, err)
+		c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+		c.JSON(int(problemDetails.Status), problemDetails)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Udm_UEID_DeconcealRspData{Supi: supi})
+}
+
+func deconcealProblemDetails(err error) *models.ProblemDetails {
+	switch {
+	case errors.Is(err, suci.ErrorUnknownKeyId):
+		return &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  unknownKeyId,
+			Detail: err.Error(),
+		}
+	case errors.Is(err, suci.ErrorMacFailure):
+		return &models.ProblemDetails{
+			Status: http.StatusForbidden,
+			Cause:  deconcealmentMacFailure,
+			Detail: err.Error(),
+		}
+	case errors.Is(err, suci.ErrorUnsupportedScheme):
+		return &models.ProblemDetails{
+			Status: http.StatusNotImplemented,
+			Cause:  unsupportedProtectionScheme,
+			Detail: err.Error(),
+		}
+	default:
+		return &models.ProblemDetails{
+			Status: http.StatusBadRequest,
+			Cause:  "MANDATORY_IE_INCORRECT",
+			Detail: err.Error(),
+		}
+	}
+}
+
+// routeSuci answers the request for a SUCI whose routing indicator the UDM does not serve, with
+// a redirect to the UDM of its group (TS 29.500 6.10.9) or a rejection, and tells whether it did
+func (p *Processor) routeSuci(c *gin.Context, supiOrSuci string) bool {
+	served, apiRoot := p.Context().RouteSuci(supiOrSuci)
+	if served {
+		return false
+	}
+
+	if apiRoot != "" {
+		location := strings.TrimSuffix(apiRoot, "/") + c.Request.URL.RequestURI()
+		logger.UeidLog.Infof("Redirect suci[%s] to [%s]", supiOrSuci, location)
+		c.Redirect(http.StatusTemporaryRedirect, location)
+		return true
+	}
+
+	problemDetails := &models.ProblemDetails{
+		Status: http.StatusForbidden,
+		Cause:  routingIndicatorNotServed,
+		Detail: "the routing indicator of the SUCI is not served by this UDM group",
+	}
+	logger.UeidLog.Warnf("Reject suci[%s]: routing indicator not served", supiOrSuci)
+	c.Set(sbi.IN_PB_DETAILS_CTX_STR, problemDetails.Cause)
+	c.JSON(int(problemDetails.Status), problemDetails)
+	return true
+}
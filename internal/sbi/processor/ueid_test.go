@@ -0,0 +1,162 @@
+package processor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/free5gc/openapi/models"
+	udm_context "github.com/free5gc/udm/internal/context"
+	mockapp "github.com/free5gc/udm/pkg/mockapp"
+	"github.com/free5gc/udm/pkg/suci"
+)
+
+func TestDeconcealProcedure(t *testing.T) {
+	const profileAOutput = "b2e92f836055a255837debf850b528997ce0201cb82adfe4be1f587d07d8457dcb02352410cddd9e730ef3fa87"
+	suciProfiles, err := suci.NewProfileTable([]suci.SuciProfile{
+		{
+			KeyId:            1,
+			ProtectionScheme: suci.ProfileAScheme,
+			PrivateKey:       "c53c22208b61860b06c62e5406a7b330c2b577aa5558981510d128247d38bd1d",
+			PublicKey:        "5a8d38864820197c3394b92613b20b91633cbd897119273bf8e4a6f4eec0a650",
+		},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		suci         string
+		expectStatus int
+		expectSupi   string
+		expectCause  string
+	}{
+		{
+			name:         "null scheme",
+			suci:         "suci-0-208-93-0-0-0-00007487",
+			expectStatus: http.StatusOK,
+			expectSupi:   "imsi-2089300007487",
+		},
+		{
+			name:         "profile A",
+			suci:         "suci-0-208-93-0-1-1-" + profileAOutput,
+			expectStatus: http.StatusOK,
+			expectSupi:   "imsi-20893001002086",
+		},
+		{
+			name:         "unknown key ID",
+			suci:         "suci-0-208-93-0-1-2-" + profileAOutput,
+			expectStatus: http.StatusForbidden,
+			expectCause:  "UNKNOWN_KEY_ID",
+		},
+		{
+			name:         "MAC failure",
+			suci:         "suci-0-208-93-0-1-1-" + profileAOutput[:len(profileAOutput)-2] + "88",
+			expectStatus: http.StatusForbidden,
+			expectCause:  "DECONCEALMENT_MAC_FAILURE",
+		},
+		{
+			name:         "unsupported scheme",
+			suci:         "suci-0-208-93-0-e-1-" + profileAOutput,
+			expectStatus: http.StatusNotImplemented,
+			expectCause:  "UNSUPPORTED_PROTECTION_SCHEME",
+		},
+		{
+			name:         "scheme output too short",
+			suci:         "suci-0-208-93-0-1-1-b2e92f83",
+			expectStatus: http.StatusBadRequest,
+			expectCause:  "MANDATORY_IE_INCORRECT",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockApp := mockapp.NewMockApp(ctrl)
+			testProcessor, err := NewProcessor(mockApp)
+			require.NoError(t, err)
+			mockApp.EXPECT().Context().Return(
+				&udm_context.UDMContext{SuciProfiles: suciProfiles},
+			).AnyTimes()
+
+			httpRecorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(httpRecorder)
+			testProcessor.DeconcealProcedure(c, models.Udm_UEID_DeconcealReqData{Suci: tc.suci})
+
+			require.Equal(t, tc.expectStatus, httpRecorder.Code)
+			if tc.expectStatus == http.StatusOK {
+				var rsp models.Udm_UEID_DeconcealRspData
+				require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &rsp))
+				require.Equal(t, tc.expectSupi, rsp.Supi)
+				return
+			}
+			var problemDetails models.ProblemDetails
+			require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &problemDetails))
+			require.Equal(t, tc.expectCause, problemDetails.Cause)
+		})
+	}
+}
+
+func TestDeconcealProcedure_RoutingIndicator(t *testing.T) {
+	udmContext := &udm_context.UDMContext{
+		RoutingIndicators:         []string{"0", "12"},
+		RoutingIndicatorRedirects: map[string]string{"34": "https://udm2.example.org:8000/"},
+	}
+
+	testCases := []struct {
+		name           string
+		suci           string
+		expectStatus   int
+		expectLocation string
+		expectCause    string
+	}{
+		{
+			name:         "served routing indicator",
+			suci:         "suci-0-208-93-12-0-0-00007487",
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:           "routing indicator of another group",
+			suci:           "suci-0-208-93-34-0-0-00007487",
+			expectStatus:   http.StatusTemporaryRedirect,
+			expectLocation: "https://udm2.example.org:8000/nudm-ueid/v1/deconceal",
+		},
+		{
+			name:         "unknown routing indicator",
+			suci:         "suci-0-208-93-56-0-0-00007487",
+			expectStatus: http.StatusForbidden,
+			expectCause:  "ROUTING_INDICATOR_NOT_SERVED",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			mockApp := mockapp.NewMockApp(ctrl)
+			testProcessor, err := NewProcessor(mockApp)
+			require.NoError(t, err)
+			mockApp.EXPECT().Context().Return(udmContext).AnyTimes()
+
+			httpRecorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(httpRecorder)
+			c.Request = httptest.NewRequest(http.MethodPost, "/nudm-ueid/v1/deconceal", nil)
+			testProcessor.DeconcealProcedure(c, models.Udm_UEID_DeconcealReqData{Suci: tc.suci})
+			// gin writes a status without body when the handler chain ends
+			c.Writer.WriteHeaderNow()
+
+			require.Equal(t, tc.expectStatus, httpRecorder.Code)
+			require.Equal(t, tc.expectLocation, httpRecorder.Header().Get("Location"))
+			if tc.expectCause != "" {
+				var problemDetails models.ProblemDetails
+				require.NoError(t, json.Unmarshal(httpRecorder.Body.Bytes(), &problemDetails))
+				require.Equal(t, tc.expectCause, problemDetails.Cause)
+			}
+		})
+	}
+}
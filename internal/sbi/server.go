@@ -35,8 +35,9 @@ type ServerUdm interface {
 type Server struct {
 	ServerUdm
 
-	httpServer *http.Server
-	router     *gin.Engine
+	httpServer  *http.Server
+	router      *gin.Engine
+	adminServer *http.Server
 }
 
 func NewServer(udm ServerUdm, tlsKeyLogPath string) (*Server, error) {
@@ -55,6 +56,16 @@ func NewServer(udm ServerUdm, tlsKeyLogPath string) (*Server, error) {
 	}
 	s.httpServer.ErrorLog = log.New(logger.SBILog.WriterLevel(logrus.ErrorLevel), "HTTP2: ", 0)
 
+	if cfg.IsAdminEnabled() {
+		adminAddr := cfg.GetAdminBindingAddr()
+		logger.SBILog.Infof("Admin binding addr: [%s]", adminAddr)
+		if s.adminServer, err = httpwrapper.NewHttp2Server(adminAddr, "", newAdminRouter(s)); err != nil {
+			logger.InitLog.Errorf("Initialize admin HTTP server failed: %v", err)
+			return nil, err
+		}
+		s.adminServer.ErrorLog = log.New(logger.SBILog.WriterLevel(logrus.ErrorLevel), "HTTP2: ", 0)
+	}
+
 	return s, err
 }
 
@@ -70,6 +81,11 @@ func (s *Server) Run(traceCtx context.Context, wg *sync.WaitGroup) error {
 	wg.Add(1)
 	go s.startServer(wg)
 
+	if s.adminServer != nil {
+		wg.Add(1)
+		go s.startAdminServer(wg)
+	}
+
 	return nil
 }
 
@@ -105,6 +121,25 @@ func (s *Server) startServer(wg *sync.WaitGroup) {
 	logger.SBILog.Infof("SBI server (listen on %s) stopped", s.httpServer.Addr)
 }
 
+// startAdminServer serves the admin API in clear text, it is meant to be bound to an
+// address only the operator can reach
+func (s *Server) startAdminServer(wg *sync.WaitGroup) {
+	defer func() {
+		if p := recover(); p != nil {
+			// Print stack for panic to log. Fatalf() will let program exit.
+			logger.SBILog.Fatalf("panic: %v\n%s", p, string(debug.Stack()))
+			s.Terminate()
+		}
+		wg.Done()
+	}()
+
+	logger.SBILog.Infof("Start admin server (listen on %s)", s.adminServer.Addr)
+	if err := s.adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.SBILog.Errorf("Admin server error: %v", err)
+	}
+	logger.SBILog.Infof("Admin server (listen on %s) stopped", s.adminServer.Addr)
+}
+
 func (s *Server) Shutdown() {
 	s.shutdownHttpServer()
 }
@@ -120,21 +155,29 @@ func (s *Server) Stop() {
 			logger.SBILog.Errorf("Could not close SBI server: %#v", err)
 		}
 	}
+	if s.adminServer != nil {
+		logger.SBILog.Infof("Stop admin server (listen on %s)", s.adminServer.Addr)
+		toCtx, cancel := context.WithTimeout(context.Background(), defaultShutdownTimeout)
+		defer cancel()
+		if err := s.adminServer.Shutdown(toCtx); err != nil {
+			logger.SBILog.Errorf("Could not close admin server: %#v", err)
+		}
+	}
 }
 
 func (s *Server) shutdownHttpServer() {
 	const shutdownTimeout time.Duration = 2 * time.Second
 
-	if s.httpServer == nil {
-		return
-	}
-
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 
-	err := s.httpServer.Shutdown(shutdownCtx)
-	if err != nil {
-		logger.SBILog.Errorf("HTTP server shutdown failed: %+v", err)
+	for _, server := range []*http.Server{s.httpServer, s.adminServer} {
+		if server == nil {
+			continue
+		}
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.SBILog.Errorf("HTTP server shutdown failed: %+v", err)
+		}
 	}
 }
 
@@ -251,3 +294,15 @@ func newRouter(s *Server) *gin.Engine {
 
 	return router
 }
+
+// newAdminRouter serves the admin API, which is not part of the SBI: it is only reachable on
+// the admin listener and needs no NRF issued token
+func newAdminRouter(s *Server) *gin.Engine {
+	router := logger_util.NewGinWithLogrus(logger.GinLog)
+
+	udmAdminRoutes := s.getAdminRoutes()
+	udmAdminGroup := router.Group(factory.UdmAdminResUriPrefix)
+	AddService(udmAdminGroup, udmAdminRoutes)
+
+	return router
+}
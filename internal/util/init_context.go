@@ -45,7 +45,7 @@ func InitUDMContext(udmContext *context.UDMContext) {
 	udmContext.NrfUri = configuration.NrfUri
 	servingNameList := configuration.ServiceNameList
 
-	udmContext.SuciProfiles = configuration.SuciProfiles
+	udmContext.InitAuthentication(configuration)
 
 	udmContext.InitNFService(servingNameList, config.Info.Version)
 }
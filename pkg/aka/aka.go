@@ -0,0 +1,132 @@
+package aka
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/free5gc/util/milenage"
+)
+
+// Algorithm identifiers as provisioned in AuthenticationSubscription.AlgorithmId.
+const (
+	AlgorithmMilenage = "milenage"
+	AlgorithmTuak     = "tuak"
+	AlgorithmXor      = "xor"
+)
+
+const (
+	randLen = 16
+	sqnLen  = 6
+	amfLen  = 2
+	autsLen = 14
+)
+
+// The AMF used to calculate MAC-S assumes a dummy value of all zeros (TS 33.102 6.3.3)
+var resyncAMF = []byte{0x00, 0x00}
+
+// Algorithm is a set of authentication and key generation functions (TS 33.102 6.3)
+// keyed with the permanent key and operator variant of one subscriber.
+type Algorithm interface {
+	// Name returns the algorithm identifier
+	Name() string
+	// GenerateAKAParameters returns IK, CK, XRES and AUTN = SQN^AK || AMF || MAC-A
+	GenerateAKAParameters(rand, sqn, amf []byte) (ik, ck, xres, autn []byte, err error)
+	// ValidateAUTS verifies MAC-S of AUTS and returns the SQN of the USIM
+	ValidateAUTS(rand, auts []byte) (sqnMS []byte, err error)
+}
+
+// functions are the raw f1-f5 functions of an algorithm, from which
+// GenerateAKAParameters and ValidateAUTS are built for TUAK and the test algorithm.
+type functions interface {
+	f1(rand, sqn, amf []byte) (macA, macS []byte, err error)
+	f2345(rand []byte) (res, ck, ik, ak, akStar []byte, err error)
+}
+
+// New returns the algorithm named by algorithmId keyed with K.
+// op and opc carry OP/OPc for Milenage and TOP/TOPc for TUAK; when only the
+// operator variant is provisioned, its derived form is computed from K.
+// An empty identifier selects Milenage, so that subscriptions provisioned before the
+// algorithm was configurable keep working. TUAK is always sized with
+// DefaultTuakParameters.
+func New(algorithmId string, k, op, opc []byte) (Algorithm, error) {
+	if len(k) == 0 {
+		return nil, fmt.Errorf("permanent key K is missing")
+	}
+
+	switch strings.ToLower(algorithmId) {
+	case AlgorithmTuak:
+		return NewTuak(k, op, opc, DefaultTuakParameters)
+	case AlgorithmXor:
+		return NewXor(k)
+	case AlgorithmMilenage, "":
+		return NewMilenage(k, op, opc)
+	default:
+		return nil, fmt.Errorf("unsupported algorithm [%s]", algorithmId)
+	}
+}
+
+func generateAKAParameters(f functions, rand, sqn, amf []byte) (ik, ck, xres, autn []byte, err error) {
+	if err = validateArg(rand, "RAND", randLen); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if err = validateArg(sqn, "SQN", sqnLen); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if err = validateArg(amf, "AMF", amfLen); err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	macA, _, err := f.f1(rand, sqn, amf)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	xres, ck, ik, ak, _, err := f.f2345(rand)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	autn = append(xor(sqn, ak), amf...)
+	autn = append(autn, macA...)
+	return ik, ck, xres, autn, nil
+}
+
+func validateAUTS(f functions, rand, auts []byte) (sqnMS []byte, err error) {
+	if err = validateArg(rand, "RAND", randLen); err != nil {
+		return nil, err
+	}
+	if len(auts) < autsLen {
+		return nil, fmt.Errorf("AUTS length %d is shorter than %d", len(auts), autsLen)
+	}
+
+	_, _, _, _, akStar, err := f.f2345(rand)
+	if err != nil {
+		return nil, err
+	}
+	sqnMS = xor(auts[:sqnLen], akStar)
+
+	_, xmacS, err := f.f1(rand, sqnMS, resyncAMF)
+	if err != nil {
+		return nil, err
+	}
+	macS := auts[sqnLen:]
+	if !bytes.Equal(xmacS, macS) {
+		return nil, &milenage.MACFailureError{MACName: "MAC-S", ExpectedMAC: xmacS, ExactMAC: macS}
+	}
+	return sqnMS, nil
+}
+
+func validateArg(arg []byte, argName string, expectedLen int) error {
+	if len(arg) != expectedLen {
+		return fmt.Errorf("%s length %d is not %d", argName, len(arg), expectedLen)
+	}
+	return nil
+}
+
+func xor(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
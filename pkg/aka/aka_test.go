@@ -0,0 +1,197 @@
+package aka
+
+import (
+	"crypto/sha3"
+	"encoding/binary"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+// TS 35.208 test set 1
+func TestMilenage(t *testing.T) {
+	k := mustHex(t, "465b5ce8b199b49faa5f0a2ee238a6bc")
+	op := mustHex(t, "cdc202d5123e20f62b6d676ac72cb318")
+	rand := mustHex(t, "23553cbe9637a89d218ae64dae47bf35")
+	sqn := mustHex(t, "ff9bb4d0b607")
+	amf := mustHex(t, "b9b9")
+
+	testCases := []struct {
+		name string
+		op   []byte
+		opc  []byte
+	}{
+		{name: "OPc provisioned", opc: mustHex(t, "cd63cb71954a9f4e48a5994e37a02baf")},
+		{name: "OP provisioned", op: op},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			alg, err := New(AlgorithmMilenage, k, tc.op, tc.opc)
+			require.NoError(t, err)
+			require.Equal(t, AlgorithmMilenage, alg.Name())
+
+			ik, ck, xres, autn, err := alg.GenerateAKAParameters(rand, sqn, amf)
+			require.NoError(t, err)
+			require.Equal(t, "a54211d5e3ba50bf", hex.EncodeToString(xres))
+			require.Equal(t, "b40ba9a3c58b2a05bbf0d987b21bf8cb", hex.EncodeToString(ck))
+			require.Equal(t, "f769bcd751044604127672711c6d3441", hex.EncodeToString(ik))
+			// SQN xor AK || AMF || MAC-A, AK = aa689c648370
+			require.Equal(t, "55f328b43577b9b94a9ffac354dfafb3", hex.EncodeToString(autn))
+		})
+	}
+}
+
+func TestNewMissingKeyMaterial(t *testing.T) {
+	k := mustHex(t, "465b5ce8b199b49faa5f0a2ee238a6bc")
+
+	_, err := New("", nil, nil, nil)
+	require.ErrorContains(t, err, "K is missing")
+	_, err = New(AlgorithmMilenage, k, nil, nil)
+	require.ErrorContains(t, err, "neither OPc nor OP")
+	_, err = New(AlgorithmTuak, k, nil, nil)
+	require.ErrorContains(t, err, "neither TOPc nor TOP")
+
+	// an empty identifier selects Milenage, an unknown one is rejected
+	opc := mustHex(t, "cd63cb71954a9f4e48a5994e37a02baf")
+	alg, err := New("", k, nil, opc)
+	require.NoError(t, err)
+	require.Equal(t, AlgorithmMilenage, alg.Name())
+	_, err = New("128-EEA0", k, nil, opc)
+	require.ErrorContains(t, err, "unsupported algorithm")
+}
+
+// TS 34.108 8.1.2 with RAND = 0, so that XDOUT = K
+func TestXor(t *testing.T) {
+	k := mustHex(t, "000102030405060708090a0b0c0d0e0f")
+	rand := make([]byte, 16)
+	sqn := mustHex(t, "000000000021")
+	amf := mustHex(t, "8000")
+
+	alg, err := New(AlgorithmXor, k, nil, nil)
+	require.NoError(t, err)
+
+	ik, ck, xres, autn, err := alg.GenerateAKAParameters(rand, sqn, amf)
+	require.NoError(t, err)
+	require.Equal(t, "000102030405060708090a0b0c0d0e0f", hex.EncodeToString(xres))
+	require.Equal(t, "0102030405060708090a0b0c0d0e0f00", hex.EncodeToString(ck))
+	require.Equal(t, "02030405060708090a0b0c0d0e0f0001", hex.EncodeToString(ik))
+	// SQN xor AK || AMF || XDOUT[0..63] xor (SQN || AMF)
+	require.Equal(t, "030405060729"+"8000"+"0001020304248607", hex.EncodeToString(autn))
+}
+
+// The Keccak permutation is checked through a single block SHA3-256 sponge
+func TestKeccakF1600(t *testing.T) {
+	msg := []byte("TUAK1.0")
+
+	var buf [keccakStateLen]byte
+	copy(buf[:], msg)
+	buf[len(msg)] ^= 0x06
+	buf[135] ^= 0x80
+
+	var state [25]uint64
+	for i := range state {
+		state[i] = binary.LittleEndian.Uint64(buf[8*i:])
+	}
+	keccakF1600(&state)
+	for i := range state {
+		binary.LittleEndian.PutUint64(buf[8*i:], state[i])
+	}
+
+	expected := sha3.Sum256(msg)
+	require.Equal(t, expected[:], buf[:32])
+}
+
+func TestTuak(t *testing.T) {
+	k := mustHex(t, "abababababababababababababababab")
+	top := mustHex(t, "5555555555555555555555555555555555555555555555555555555555555555")
+	rand := mustHex(t, "42424242424242424242424242424242")
+	sqn := mustHex(t, "111111111111")
+	amf := mustHex(t, "ffff")
+
+	// TS 35.232 Test Set 1
+	testSet1, err := NewTuak(k, top, nil,
+		TuakParameters{MacLen: 8, ResLen: 4, CkLen: 16, IkLen: 16, KeccakIterations: 1})
+	require.NoError(t, err)
+	require.Equal(t, mustHex(t, "bd04d9530e87513c5d837ac2ad954623a8e2330c115305a73eb45d1f40cccbff"),
+		testSet1.TOPc())
+	macA, macS, err := testSet1.f1(rand, sqn, amf)
+	require.NoError(t, err)
+	require.Equal(t, mustHex(t, "f9a54e6aeaa8618d"), macA)
+	require.Equal(t, mustHex(t, "e94b4dc6c7297df3"), macS)
+	res, ck, ik, ak, akStar, err := testSet1.f2345(rand)
+	require.NoError(t, err)
+	require.Equal(t, mustHex(t, "657acd64"), res)
+	require.Equal(t, mustHex(t, "d71a1e5c6caffe986a26f783e5c78be1"), ck)
+	require.Equal(t, mustHex(t, "be849fa2564f869aecee6f62d4337e72"), ik)
+	require.Equal(t, mustHex(t, "719f1e9b9054"), ak)
+	require.Equal(t, mustHex(t, "e7af6b3d0e38"), akStar)
+
+	fromTop, err := NewTuak(k, top, nil, DefaultTuakParameters)
+	require.NoError(t, err)
+	fromTopc, err := NewTuak(k, nil, fromTop.TOPc(), DefaultTuakParameters)
+	require.NoError(t, err)
+
+	ik1, ck1, xres1, autn1, err := fromTop.GenerateAKAParameters(rand, sqn, amf)
+	require.NoError(t, err)
+	ik2, ck2, xres2, autn2, err := fromTopc.GenerateAKAParameters(rand, sqn, amf)
+	require.NoError(t, err)
+	require.Equal(t, ik1, ik2)
+	require.Equal(t, ck1, ck2)
+	require.Equal(t, xres1, xres2)
+	require.Equal(t, autn1, autn2)
+	require.Len(t, xres1, DefaultTuakParameters.ResLen)
+	require.Len(t, autn1, 16)
+
+	params := TuakParameters{MacLen: 16, ResLen: 16, CkLen: 32, IkLen: 32, KeccakIterations: 2}
+	long, err := NewTuak(k, top, nil, params)
+	require.NoError(t, err)
+	ik, ck, xres, autn, err := long.GenerateAKAParameters(rand, sqn, amf)
+	require.NoError(t, err)
+	require.Len(t, ik, 32)
+	require.Len(t, ck, 32)
+	require.Len(t, xres, 16)
+	require.Len(t, autn, 24)
+}
+
+func TestValidateAUTS(t *testing.T) {
+	k := mustHex(t, "465b5ce8b199b49faa5f0a2ee238a6bc")
+	rand := mustHex(t, "23553cbe9637a89d218ae64dae47bf35")
+	sqnMS := mustHex(t, "0000000003e0")
+
+	tuak, err := NewTuak(k, mustHex(t, "5555555555555555555555555555555555555555555555555555555555555555"),
+		nil, DefaultTuakParameters)
+	require.NoError(t, err)
+	xorAlg, err := NewXor(k)
+	require.NoError(t, err)
+
+	for _, f := range []interface {
+		Algorithm
+		functions
+	}{tuak, xorAlg} {
+		t.Run(f.Name(), func(t *testing.T) {
+			// AUTS = SQN_MS xor AK* || MAC-S as built by the USIM
+			_, macS, err := f.f1(rand, sqnMS, resyncAMF)
+			require.NoError(t, err)
+			_, _, _, _, akStar, err := f.f2345(rand)
+			require.NoError(t, err)
+			auts := append(xor(sqnMS, akStar), macS...)
+
+			got, err := f.ValidateAUTS(rand, auts)
+			require.NoError(t, err)
+			require.Equal(t, sqnMS, got)
+
+			auts[len(auts)-1] ^= 0x01
+			_, err = f.ValidateAUTS(rand, auts)
+			require.ErrorContains(t, err, "MAC-S")
+		})
+	}
+}
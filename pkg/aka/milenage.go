@@ -0,0 +1,58 @@
+package aka
+
+import (
+	"fmt"
+
+	"github.com/free5gc/util/milenage"
+)
+
+const (
+	opLen  = 16
+	opcLen = 16
+)
+
+// Milenage is the example algorithm set of TS 35.205/35.206.
+type Milenage struct {
+	k   []byte
+	opc []byte
+}
+
+var _ Algorithm = &Milenage{}
+
+// NewMilenage keys Milenage with K and OPc, deriving OPc from OP when OPc is not provisioned.
+func NewMilenage(k, op, opc []byte) (*Milenage, error) {
+	if len(opc) == 0 {
+		if len(op) == 0 {
+			return nil, fmt.Errorf("neither OPc nor OP is provisioned for Milenage")
+		}
+		if err := validateArg(op, "OP", opLen); err != nil {
+			return nil, err
+		}
+		var err error
+		opc, err = milenage.GenerateOPc(k, op)
+		if err != nil {
+			return nil, fmt.Errorf("derive OPc from OP: %w", err)
+		}
+	} else if err := validateArg(opc, "OPc", opcLen); err != nil {
+		return nil, err
+	}
+
+	return &Milenage{k: k, opc: opc}, nil
+}
+
+func (m *Milenage) Name() string {
+	return AlgorithmMilenage
+}
+
+// OPc returns the operator variant the algorithm is keyed with
+func (m *Milenage) OPc() []byte {
+	return m.opc
+}
+
+func (m *Milenage) GenerateAKAParameters(rand, sqn, amf []byte) (ik, ck, xres, autn []byte, err error) {
+	return milenage.GenerateAKAParameters(m.opc, m.k, rand, sqn, amf)
+}
+
+func (m *Milenage) ValidateAUTS(rand, auts []byte) (sqnMS []byte, err error) {
+	return milenage.ValidateAUTS(m.opc, m.k, rand, auts)
+}
@@ -0,0 +1,262 @@
+package aka
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+const (
+	topLen  = 32
+	topcLen = 32
+	// Keccak state size in bytes (1600 bits)
+	keccakStateLen = 200
+)
+
+var tuakAlgoName = []byte("TUAK1.0")
+
+// TuakParameters are the output lengths (in bytes) and the number of Keccak
+// iterations a TUAK subscriber is provisioned with (TS 35.231 5.2).
+type TuakParameters struct {
+	MacLen           int
+	ResLen           int
+	CkLen            int
+	IkLen            int
+	KeccakIterations int
+}
+
+// DefaultTuakParameters sizes the outputs like Milenage does. They are not configurable:
+// USIMs personalized with other output lengths or Keccak iterations are not supported.
+var DefaultTuakParameters = TuakParameters{
+	MacLen:           8,
+	ResLen:           8,
+	CkLen:            16,
+	IkLen:            16,
+	KeccakIterations: 1,
+}
+
+// Tuak is the Keccak based algorithm set of TS 35.231.
+type Tuak struct {
+	k      []byte
+	topc   []byte
+	params TuakParameters
+}
+
+var (
+	_ Algorithm = &Tuak{}
+	_ functions = &Tuak{}
+)
+
+// NewTuak keys TUAK with K (128 or 256 bits) and TOPc, deriving TOPc from TOP when
+// TOPc is not provisioned.
+func NewTuak(k, top, topc []byte, params TuakParameters) (*Tuak, error) {
+	if len(k) != 16 && len(k) != 32 {
+		return nil, fmt.Errorf("K length %d is neither 16 nor 32", len(k))
+	}
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	t := &Tuak{k: k, params: params}
+	if len(topc) == 0 {
+		if len(top) == 0 {
+			return nil, fmt.Errorf("neither TOPc nor TOP is provisioned for TUAK")
+		}
+		if err := validateArg(top, "TOP", topLen); err != nil {
+			return nil, err
+		}
+		t.topc = t.computeTopc(top)
+	} else {
+		if err := validateArg(topc, "TOPc", topcLen); err != nil {
+			return nil, err
+		}
+		t.topc = topc
+	}
+
+	return t, nil
+}
+
+func (p TuakParameters) validate() error {
+	switch p.MacLen {
+	case 8, 16, 32:
+	default:
+		return fmt.Errorf("unsupported TUAK MAC length %d", p.MacLen)
+	}
+	switch p.ResLen {
+	case 4, 8, 16, 32:
+	default:
+		return fmt.Errorf("unsupported TUAK RES length %d", p.ResLen)
+	}
+	if p.CkLen != 16 && p.CkLen != 32 {
+		return fmt.Errorf("unsupported TUAK CK length %d", p.CkLen)
+	}
+	if p.IkLen != 16 && p.IkLen != 32 {
+		return fmt.Errorf("unsupported TUAK IK length %d", p.IkLen)
+	}
+	if p.KeccakIterations < 1 {
+		return fmt.Errorf("TUAK needs at least one Keccak iteration")
+	}
+	return nil
+}
+
+func (t *Tuak) Name() string {
+	return AlgorithmTuak
+}
+
+// TOPc returns the operator variant the algorithm is keyed with
+func (t *Tuak) TOPc() []byte {
+	return t.topc
+}
+
+func (t *Tuak) GenerateAKAParameters(rand, sqn, amf []byte) (ik, ck, xres, autn []byte, err error) {
+	return generateAKAParameters(t, rand, sqn, amf)
+}
+
+func (t *Tuak) ValidateAUTS(rand, auts []byte) (sqnMS []byte, err error) {
+	return validateAUTS(t, rand, auts)
+}
+
+// instance builds the INSTANCE octet of TS 35.231 Table 6.2
+func (t *Tuak) instance(base byte) byte {
+	if len(t.k) == 32 {
+		base |= 0x01
+	}
+	return base
+}
+
+func lenCode(n int) byte {
+	switch n {
+	case 8:
+		return 0x08
+	case 16:
+		return 0x10
+	case 32:
+		return 0x20
+	}
+	return 0x00
+}
+
+func (t *Tuak) computeTopc(top []byte) []byte {
+	out := t.core(top, t.instance(0x00), nil, nil, nil)
+	return reverse(out[:topcLen])
+}
+
+func (t *Tuak) f1(rand, sqn, amf []byte) (macA, macS []byte, err error) {
+	out := t.core(t.topc, t.instance(lenCode(t.params.MacLen)), rand, amf, sqn)
+	macA = reverse(out[:t.params.MacLen])
+
+	out = t.core(t.topc, t.instance(0x80|lenCode(t.params.MacLen)), rand, amf, sqn)
+	macS = reverse(out[:t.params.MacLen])
+	return macA, macS, nil
+}
+
+func (t *Tuak) f2345(rand []byte) (res, ck, ik, ak, akStar []byte, err error) {
+	inst := 0x40 | lenCode(t.params.ResLen)
+	if t.params.CkLen == 32 {
+		inst |= 0x04
+	}
+	if t.params.IkLen == 32 {
+		inst |= 0x02
+	}
+	out := t.core(t.topc, t.instance(inst), rand, nil, nil)
+	// TS 35.231 6.4: RES, CK, IK and AK start at bits 0, 256, 512 and 768 of OUT
+	res = reverse(out[0:t.params.ResLen])
+	ck = reverse(out[32 : 32+t.params.CkLen])
+	ik = reverse(out[64 : 64+t.params.IkLen])
+	ak = reverse(out[96 : 96+sqnLen])
+
+	out = t.core(t.topc, t.instance(0xc0), rand, nil, nil)
+	akStar = reverse(out[96 : 96+sqnLen])
+	return res, ck, ik, ak, akStar, nil
+}
+
+// core lays out INOUT as specified by TS 35.231 6.3-6.6 and runs Keccak-f[1600] on it.
+// Values are stored least significant byte first, matching the bit numbering of the spec.
+func (t *Tuak) core(topc []byte, instance byte, rand, amf, sqn []byte) []byte {
+	var buf [keccakStateLen]byte
+	copy(buf[0:32], reverse(topc))
+	buf[32] = instance
+	copy(buf[33:40], reverse(tuakAlgoName))
+	if rand != nil {
+		copy(buf[40:56], reverse(rand))
+	}
+	if amf != nil {
+		copy(buf[56:58], reverse(amf))
+	}
+	if sqn != nil {
+		copy(buf[58:64], reverse(sqn))
+	}
+	copy(buf[64:64+len(t.k)], reverse(t.k))
+	buf[96] = 0x1f
+	buf[135] = 0x80
+
+	var state [25]uint64
+	for i := range state {
+		state[i] = binary.LittleEndian.Uint64(buf[8*i:])
+	}
+	for i := 0; i < t.params.KeccakIterations; i++ {
+		keccakF1600(&state)
+	}
+	for i := range state {
+		binary.LittleEndian.PutUint64(buf[8*i:], state[i])
+	}
+	return buf[:]
+}
+
+func reverse(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[len(b)-1-i] = b[i]
+	}
+	return out
+}
+
+var keccakRoundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808A, 0x8000000080008000,
+	0x000000000000808B, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008A, 0x0000000000000088, 0x0000000080008009, 0x000000008000000A,
+	0x000000008000808B, 0x800000000000008B, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800A, 0x800000008000000A,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+// keccakRotations[x][y] are the rho offsets of lane (x, y)
+var keccakRotations = [5][5]int{
+	{0, 36, 3, 41, 18},
+	{1, 44, 10, 45, 2},
+	{62, 6, 43, 15, 61},
+	{28, 55, 25, 21, 56},
+	{27, 20, 39, 8, 14},
+}
+
+// keccakF1600 is the Keccak-f[1600] permutation; lane (x, y) is a[x+5*y]
+func keccakF1600(a *[25]uint64) {
+	var c [5]uint64
+	var b [25]uint64
+	for round := 0; round < 24; round++ {
+		// theta
+		for x := 0; x < 5; x++ {
+			c[x] = a[x] ^ a[x+5] ^ a[x+10] ^ a[x+15] ^ a[x+20]
+		}
+		for x := 0; x < 5; x++ {
+			d := c[(x+4)%5] ^ bits.RotateLeft64(c[(x+1)%5], 1)
+			for y := 0; y < 25; y += 5 {
+				a[y+x] ^= d
+			}
+		}
+		// rho and pi
+		for x := 0; x < 5; x++ {
+			for y := 0; y < 5; y++ {
+				b[y+5*((2*x+3*y)%5)] = bits.RotateLeft64(a[x+5*y], keccakRotations[x][y])
+			}
+		}
+		// chi
+		for y := 0; y < 25; y += 5 {
+			for x := 0; x < 5; x++ {
+				a[y+x] = b[y+x] ^ (^b[y+(x+1)%5] & b[y+(x+2)%5])
+			}
+		}
+		// iota
+		a[0] ^= keccakRoundConstants[round]
+	}
+}
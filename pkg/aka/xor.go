@@ -0,0 +1,57 @@
+package aka
+
+import (
+	"fmt"
+)
+
+const xorKeyLen = 16
+
+// Xor is the test algorithm of TS 34.108 8.1.2 used by test USIMs.
+// It offers no security and must only be provisioned for lab subscribers.
+type Xor struct {
+	k []byte
+}
+
+var (
+	_ Algorithm = &Xor{}
+	_ functions = &Xor{}
+)
+
+func NewXor(k []byte) (*Xor, error) {
+	if len(k) != xorKeyLen {
+		return nil, fmt.Errorf("K length %d is not %d", len(k), xorKeyLen)
+	}
+	return &Xor{k: k}, nil
+}
+
+func (x *Xor) Name() string {
+	return AlgorithmXor
+}
+
+func (x *Xor) GenerateAKAParameters(rand, sqn, amf []byte) (ik, ck, xres, autn []byte, err error) {
+	return generateAKAParameters(x, rand, sqn, amf)
+}
+
+func (x *Xor) ValidateAUTS(rand, auts []byte) (sqnMS []byte, err error) {
+	return validateAUTS(x, rand, auts)
+}
+
+// f1 computes XMAC = XDOUT[0..63] xor CDOUT[0..63], with CDOUT = SQN || AMF.
+// The test algorithm uses the same function for f1 and f1*.
+func (x *Xor) f1(rand, sqn, amf []byte) (macA, macS []byte, err error) {
+	xdout := xor(x.k, rand)
+	cdout := append(append([]byte{}, sqn...), amf...)
+	mac := xor(xdout[:8], cdout)
+	return mac, mac, nil
+}
+
+// f2345 derives RES, CK, IK and AK from XDOUT = K xor RAND.
+// The test algorithm uses the same function for f5 and f5*.
+func (x *Xor) f2345(rand []byte) (res, ck, ik, ak, akStar []byte, err error) {
+	xdout := xor(x.k, rand)
+	res = xdout
+	ck = append(append([]byte{}, xdout[1:]...), xdout[:1]...)
+	ik = append(append([]byte{}, xdout[2:]...), xdout[:2]...)
+	ak = append([]byte{}, xdout[3:9]...)
+	return res, ck, ik, ak, ak, nil
+}
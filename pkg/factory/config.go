@@ -9,11 +9,15 @@ import (
 	"os"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/asaskevich/govalidator"
 	"github.com/google/uuid"
 
 	"github.com/free5gc/udm/internal/logger"
+	"github.com/free5gc/udm/pkg/keyprovider"
+	"github.com/free5gc/udm/pkg/servingnetwork"
+	"github.com/free5gc/udm/pkg/sqn"
 	"github.com/free5gc/udm/pkg/suci"
 )
 
@@ -30,6 +34,8 @@ const (
 	UdmMetricsDefaultPort         = 9091
 	UdmMetricsDefaultScheme       = "https"
 	UdmMetricsDefaultNamespace    = "free5gc"
+	UdmAdminDefaultIPv4           = "127.0.0.1"
+	UdmAdminDefaultPort           = 8001
 	UdmDefaultNrfUri              = "https://127.0.0.10:8000"
 	UdmSorprotectionResUriPrefix  = "/nudm-sorprotection/v1"
 	UdmAuthResUriPrefix           = "/nudm-auth/v1"
@@ -46,6 +52,7 @@ const (
 	UdmRsdsResUriPrefix           = "/nudm-rsds/v1"
 	UdmSsauResUriPrefix           = "/nudm-ssau/v1"
 	UdmUeidResUriPrefix           = "/nudm-ueid/v1"
+	UdmAdminResUriPrefix          = "/udm-admin/v1"
 )
 
 type Config struct {
@@ -72,14 +79,171 @@ type Info struct {
 }
 
 type Configuration struct {
-	NfInstanceId    string             `yaml:"nfInstanceId,omitempty" valid:"optional,uuidv4"`
-	Sbi             *Sbi               `yaml:"sbi,omitempty"  valid:"required"`
-	Metrics         *Metrics           `yaml:"metrics,omitempty" valid:"optional"`
-	ServiceNameList []string           `yaml:"serviceNameList,omitempty"  valid:"required"`
-	NrfUri          string             `yaml:"nrfUri,omitempty"  valid:"required, url"`
-	NrfCertPem      string             `yaml:"nrfCertPem,omitempty" valid:"optional"`
-	SuciProfiles    []suci.SuciProfile `yaml:"SuciProfile,omitempty"`
+	NfInstanceId      string             `yaml:"nfInstanceId,omitempty" valid:"optional,uuidv4"`
+	Sbi               *Sbi               `yaml:"sbi,omitempty"  valid:"required"`
+	Metrics           *Metrics           `yaml:"metrics,omitempty" valid:"optional"`
+	Admin             *Admin             `yaml:"admin,omitempty" valid:"optional"`
+	ServiceNameList   []string           `yaml:"serviceNameList,omitempty"  valid:"required"`
+	NrfUri            string             `yaml:"nrfUri,omitempty"  valid:"required, url"`
+	NrfCertPem        string             `yaml:"nrfCertPem,omitempty" valid:"optional"`
+	SuciProfiles      []suci.SuciProfile `yaml:"SuciProfile,omitempty"`
+	OperatorKeys      *OperatorKeys      `yaml:"operatorKeys,omitempty" valid:"optional"`
+	KeyProvider       *KeyProvider       `yaml:"keyProvider,omitempty" valid:"optional"`
+	PlaintextKeyIds   []string           `yaml:"plaintextProtectionParameterIds,omitempty" valid:"optional"`
+	SqnScheme         *SqnScheme         `yaml:"sqnScheme,omitempty" valid:"optional"`
+	AuthFailurePolicy *AuthFailurePolicy `yaml:"authFailurePolicy,omitempty" valid:"optional"`
+	ServingNetworks   *ServingNetworks   `yaml:"servingNetworks,omitempty" valid:"optional"`
+	UdmGroup          *UdmGroup          `yaml:"udmGroup,omitempty" valid:"optional"`
+}
+
+// OperatorKeys hold the operator variant algorithm configuration fields. They are used to
+// derive OPc (TOPc for TUAK) from K for subscriptions provisioned without it.
+type OperatorKeys struct {
+	Op  string `yaml:"op,omitempty" valid:"optional"`
+	Top string `yaml:"top,omitempty" valid:"optional"`
+}
+
+// KeyProvider configures the decryption of the subscriber keys stored encrypted in the UDR,
+// the protectionParameterId of a subscription names the key to decrypt with.
+// Keys are stored in plaintext when the subscription has no protection parameter ID or one
+// listed in plaintextProtectionParameterIds.
+// Only the AES file keystore can be configured. PKCS#11 is out of scope of the configuration:
+// the UDM ships no PKCS#11 binding to load a module and log into a slot with, so a
+// keyprovider.Pkcs11Provider is set on the UDM context by the code embedding the UDM.
+type KeyProvider struct {
+	Type     string `yaml:"type" valid:"required,in(file)"`
+	Keystore string `yaml:"keystore,omitempty" valid:"type(string),minstringlength(1),required"`
+}
+
+// SqnScheme configures the SEQ/IND sequence number scheme of TS 33.102 Annex C,
+// unset fields take the values recommended in C.3.2.
+type SqnScheme struct {
+	// IndLength is the number of IND bits of SQN
+	IndLength *uint `yaml:"indLength,omitempty" valid:"optional"`
+	// Delta is the largest SEQ increase accepted by the USIM
+	Delta uint64 `yaml:"delta,omitempty" valid:"optional"`
+	// AgeLimit is the age limit L of the USIM, 0 disables it
+	AgeLimit uint64 `yaml:"ageLimit,omitempty" valid:"optional"`
+}
+
+// Scheme returns the sqn.Scheme described by the configuration
+func (s *SqnScheme) Scheme() sqn.Scheme {
+	scheme := sqn.DefaultScheme
+	if s.IndLength != nil {
+		scheme.IndLength = *s.IndLength
+	}
+	if s.Delta != 0 {
+		scheme.Delta = s.Delta
+	}
+	scheme.AgeLimit = s.AgeLimit
+	return scheme
+}
+
+// AuthFailurePolicy sets the number of failed re-synchronizations (wrong MAC-S) and of
+// authentications confirmed as failed after which a UE is rejected for lockoutDuration.
+// A zero threshold disables the corresponding counter.
+type AuthFailurePolicy struct {
+	MaxResyncFailures       int           `yaml:"maxResyncFailures,omitempty" valid:"optional"`
+	MaxConfirmationFailures int           `yaml:"maxConfirmationFailures,omitempty" valid:"optional"`
+	LockoutDuration         time.Duration `yaml:"lockoutDuration,omitempty" valid:"optional"`
+}
+
+// ServingNetworks restricts the serving networks authentication vectors are generated for.
+// The home networks are always allowed; a visited network needs a roaming agreement, and
+// the subscriber must not be barred from roaming by its access and mobility subscription data.
+// Without this section any well-formed serving network name is accepted.
+type ServingNetworks struct {
+	HomeNetworks      []ServingNetwork `yaml:"homeNetworks,omitempty" valid:"optional"`
+	RoamingAgreements []ServingNetwork `yaml:"roamingAgreements,omitempty" valid:"optional"`
+}
+
+// ServingNetwork is a PLMN, nidList names the SNPNs it hosts that are covered as well
+type ServingNetwork struct {
+	Mcc     string   `yaml:"mcc" valid:"optional"`
+	Mnc     string   `yaml:"mnc" valid:"optional"`
+	NidList []string `yaml:"nidList,omitempty" valid:"optional"`
+}
+
+// Policy returns the servingnetwork.Policy described by the configuration
+func (s *ServingNetworks) Policy() servingnetwork.Policy {
+	convert := func(networks []ServingNetwork) []servingnetwork.Network {
+		converted := make([]servingnetwork.Network, 0, len(networks))
+		for _, nw := range networks {
+			converted = append(converted, servingnetwork.Network{Mcc: nw.Mcc, Mnc: nw.Mnc, Nids: nw.NidList})
+		}
+		return converted
+	}
+	return servingnetwork.Policy{
+		HomeNetworks:      convert(s.HomeNetworks),
+		RoamingAgreements: convert(s.RoamingAgreements),
+	}
+}
+
+func (s *ServingNetworks) validate() error {
+	if len(s.HomeNetworks) == 0 {
+		return fmt.Errorf("invalid ServingNetworks, homeNetworks should not be empty")
+	}
+	for _, nw := range append(append([]ServingNetwork{}, s.HomeNetworks...), s.RoamingAgreements...) {
+		if !govalidator.StringMatches(nw.Mcc, "^[0-9]{3}$") || !govalidator.StringMatches(nw.Mnc, "^[0-9]{2,3}$") {
+			return fmt.Errorf("invalid ServingNetworks PLMN [%s-%s], should be a 3-digit mcc and a 2 or 3-digit mnc",
+				nw.Mcc, nw.Mnc)
+		}
+		for _, nid := range nw.NidList {
+			if !govalidator.StringMatches(nid, "^[0-9A-Fa-f]{11}$") {
+				return fmt.Errorf("invalid ServingNetworks NID [%s], should be 11 hexadecimal digits", nid)
+			}
+		}
+	}
+	return nil
+}
+
+// UdmGroup is the UDM group of this instance (TS 23.501 6.3.8) and the routing indicators of
+// the SUCIs it serves, both advertised to the NRF. A SUCI of another routing indicator is
+// redirected to the UDM of its group when a redirect covers it, and rejected otherwise.
+// Without routingIndicators every SUCI is served.
+type UdmGroup struct {
+	GroupId           string             `yaml:"groupId,omitempty" valid:"optional"`
+	RoutingIndicators []string           `yaml:"routingIndicators,omitempty" valid:"optional"`
+	Redirects         []UdmGroupRedirect `yaml:"redirects,omitempty" valid:"optional"`
+}
+
+// UdmGroupRedirect sends the requests for the SUCIs of routingIndicators to the UDM at apiRoot
+type UdmGroupRedirect struct {
+	RoutingIndicators []string `yaml:"routingIndicators" valid:"optional"`
+	ApiRoot           string   `yaml:"apiRoot" valid:"optional"`
 }
+
+func (g *UdmGroup) validate() error {
+	served := make(map[string]bool)
+	for _, ri := range g.RoutingIndicators {
+		if !govalidator.StringMatches(ri, "^[0-9]{1,4}$") {
+			return fmt.Errorf("invalid UdmGroup routing indicator [%s], should be 1 to 4 digits", ri)
+		}
+		served[ri] = true
+	}
+	if len(g.Redirects) > 0 && len(served) == 0 {
+		return fmt.Errorf("invalid UdmGroup, redirects need the routingIndicators served locally")
+	}
+	for _, redirect := range g.Redirects {
+		if !govalidator.IsURL(redirect.ApiRoot) {
+			return fmt.Errorf("invalid UdmGroup redirect apiRoot [%s], should be a URL", redirect.ApiRoot)
+		}
+		if len(redirect.RoutingIndicators) == 0 {
+			return fmt.Errorf("invalid UdmGroup redirect to [%s], routingIndicators should not be empty",
+				redirect.ApiRoot)
+		}
+		for _, ri := range redirect.RoutingIndicators {
+			if !govalidator.StringMatches(ri, "^[0-9]{1,4}$") {
+				return fmt.Errorf("invalid UdmGroup routing indicator [%s], should be 1 to 4 digits", ri)
+			}
+			if served[ri] {
+				return fmt.Errorf("invalid UdmGroup redirect of routing indicator [%s], it is served locally", ri)
+			}
+		}
+	}
+	return nil
+}
+
 type Logger struct {
 	Enable       bool   `yaml:"enable" valid:"type(bool)"`
 	Level        string `yaml:"level" valid:"required,in(trace|debug|info|warn|error|fatal|panic)"`
@@ -115,12 +279,27 @@ func (c *Configuration) validate() (bool, error) {
 		}
 	}
 
+	if c.Admin != nil {
+		if _, err := govalidator.ValidateStruct(c.Admin); err != nil {
+			return false, appendInvalid(err)
+		}
+
+		if c.Sbi != nil && c.Admin.Port == c.Sbi.Port && c.Sbi.BindingIPv4 == c.Admin.BindingIPv4 {
+			var errs govalidator.Errors
+			err := fmt.Errorf("sbi and admin bindings IPv4: %s and port: %d cannot be the same, "+
+				"please provide at least another port for the admin API", c.Sbi.BindingIPv4, c.Sbi.Port)
+			errs = append(errs, err)
+			return false, error(errs)
+		}
+	}
+
 	if c.ServiceNameList != nil {
 		var errs govalidator.Errors
 		for _, v := range c.ServiceNameList {
-			if v != "nudm-sdm" && v != "nudm-uecm" && v != "nudm-ueau" && v != "nudm-ee" && v != "nudm-pp" {
+			if v != "nudm-sdm" && v != "nudm-uecm" && v != "nudm-ueau" && v != "nudm-ee" && v != "nudm-pp" &&
+				v != "nudm-ueid" {
 				err := fmt.Errorf("invalid ServiceNameList: [%s],"+
-					" value should be nudm-sdm or nudm-uecm or nudm-ueau or nudm-ee or nudm-pp", v)
+					" value should be nudm-sdm or nudm-uecm or nudm-ueau or nudm-ee or nudm-pp or nudm-ueid", v)
 				errs = append(errs, err)
 			}
 		}
@@ -138,24 +317,85 @@ func (c *Configuration) validate() (bool, error) {
 				errs = append(errs, err)
 			}
 
-			privateKey := s.PrivateKey
-			if result := govalidator.StringMatches(privateKey, "^[A-Fa-f0-9]{64}$"); !result {
-				err := fmt.Errorf("invalid PrivateKey: %s, should be 64 hexadecimal digits", privateKey)
+			// the keys are checked by the decryptor of the scheme
+			if err := suci.ValidateProfile(s); err != nil {
 				errs = append(errs, err)
 			}
 
-			publicKey := s.PublicKey
-			if result := govalidator.StringMatches(publicKey, "^[A-Fa-f0-9]{64,130}$"); !result {
-				err := fmt.Errorf("invalid PublicKey: %s, should be 64(profile A), 66(profile B, compressed),"+
-					"or 130(profile B, uncompressed) hexadecimal digits", publicKey)
+			if s.KeyId != 0 && (s.KeyId < suci.MinKeyId || s.KeyId > suci.MaxKeyId) {
+				err := fmt.Errorf("invalid KeyId: %d, should be in range [%d, %d]",
+					s.KeyId, suci.MinKeyId, suci.MaxKeyId)
 				errs = append(errs, err)
 			}
 		}
+		if len(errs) == 0 {
+			if _, err := suci.NewProfileTable(c.SuciProfiles); err != nil {
+				errs = append(errs, fmt.Errorf("invalid SuciProfile: %w", err))
+			}
+		}
+		if len(errs) > 0 {
+			return false, error(errs)
+		}
+	}
+
+	if keyProvider := c.KeyProvider; keyProvider != nil && keyProvider.Type == "pkcs11" {
+		return false, error(govalidator.Errors{fmt.Errorf("invalid KeyProvider.Type: pkcs11 cannot be configured, " +
+			"the UDM has no PKCS#11 binding")})
+	}
+
+	if keyProvider := c.KeyProvider; keyProvider != nil && keyProvider.Keystore != "" {
+		// a UDM without its keystore would issue vectors from undecryptable keys
+		if _, err := keyprovider.LoadFileKeyStore(keyProvider.Keystore); err != nil {
+			return false, error(govalidator.Errors{fmt.Errorf("invalid KeyProvider.Keystore: %w", err)})
+		}
+	}
+
+	if operatorKeys := c.OperatorKeys; operatorKeys != nil {
+		var errs govalidator.Errors
+		if operatorKeys.Op != "" && !govalidator.StringMatches(operatorKeys.Op, "^[A-Fa-f0-9]{32}$") {
+			err := fmt.Errorf("invalid OperatorKeys.Op, should be 32 hexadecimal digits")
+			errs = append(errs, err)
+		}
+		if operatorKeys.Top != "" && !govalidator.StringMatches(operatorKeys.Top, "^[A-Fa-f0-9]{64}$") {
+			err := fmt.Errorf("invalid OperatorKeys.Top, should be 64 hexadecimal digits")
+			errs = append(errs, err)
+		}
+		if len(errs) > 0 {
+			return false, error(errs)
+		}
+	}
+
+	if policy := c.AuthFailurePolicy; policy != nil {
+		var errs govalidator.Errors
+		if policy.MaxResyncFailures < 0 || policy.MaxConfirmationFailures < 0 {
+			errs = append(errs, fmt.Errorf("invalid AuthFailurePolicy, thresholds should not be negative"))
+		}
+		if policy.LockoutDuration <= 0 {
+			errs = append(errs, fmt.Errorf("invalid AuthFailurePolicy.LockoutDuration, should be positive, e.g. 5m"))
+		}
 		if len(errs) > 0 {
 			return false, error(errs)
 		}
 	}
 
+	if servingNetworks := c.ServingNetworks; servingNetworks != nil {
+		if err := servingNetworks.validate(); err != nil {
+			return false, error(govalidator.Errors{err})
+		}
+	}
+
+	if udmGroup := c.UdmGroup; udmGroup != nil {
+		if err := udmGroup.validate(); err != nil {
+			return false, error(govalidator.Errors{err})
+		}
+	}
+
+	if sqnScheme := c.SqnScheme; sqnScheme != nil {
+		if err := sqnScheme.Scheme().Validate(); err != nil {
+			return false, error(govalidator.Errors{fmt.Errorf("invalid SqnScheme: %w", err)})
+		}
+	}
+
 	result, err := govalidator.ValidateStruct(c)
 	return result, err
 }
@@ -236,6 +476,15 @@ type Metrics struct {
 	Namespace   string `yaml:"namespace" valid:"optional"`
 }
 
+// Admin serves the operator API of the UDM, the authentication failure counters, on a
+// listener of its own: it is not an SBI service, so access to it is controlled by where
+// it is bound rather than by NRF issued tokens. The API is not served unless enabled.
+type Admin struct {
+	Enable      bool   `yaml:"enable" valid:"optional"`
+	BindingIPv4 string `yaml:"bindingIPv4,omitempty" valid:"optional,host"`
+	Port        int    `yaml:"port,omitempty" valid:"optional,port"`
+}
+
 // This function is the mirror of the SBI one, I decided not to factor the code as it could in the future diverge.
 // And it will reduce the cognitive overload when reading the function by not hiding the logic elsewhere.
 func (m *Metrics) validate() (bool, error) {
@@ -406,6 +655,33 @@ func (c *Config) AreMetricsEnabled() bool {
 	return UdmMetricsDefaultEnabled
 }
 
+func (c *Config) IsAdminEnabled() bool {
+	c.RLock()
+	defer c.RUnlock()
+	return c.Configuration != nil && c.Configuration.Admin != nil && c.Configuration.Admin.Enable
+}
+
+func (c *Config) GetAdminBindingAddr() string {
+	c.RLock()
+	defer c.RUnlock()
+	bindIP, port := UdmAdminDefaultIPv4, UdmAdminDefaultPort
+	if c.Configuration == nil || c.Configuration.Admin == nil {
+		return bindIP + ":" + strconv.Itoa(port)
+	}
+
+	if admin := c.Configuration.Admin; admin.BindingIPv4 != "" {
+		if bindIP = os.Getenv(admin.BindingIPv4); bindIP != "" {
+			logger.CfgLog.Infof("Parsing admin IPv4 [%s] from ENV Variable", bindIP)
+		} else {
+			bindIP = admin.BindingIPv4
+		}
+	}
+	if c.Configuration.Admin.Port != 0 {
+		port = c.Configuration.Admin.Port
+	}
+	return bindIP + ":" + strconv.Itoa(port)
+}
+
 func (c *Config) GetMetricsScheme() string {
 	c.RLock()
 	defer c.RUnlock()
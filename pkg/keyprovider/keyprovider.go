@@ -0,0 +1,132 @@
+package keyprovider
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Algorithms a protection key can be provisioned with
+const (
+	// AlgorithmAesEcb decrypts block aligned ciphertext, as found in SIM vendor output files
+	AlgorithmAesEcb = "aes-ecb"
+	// AlgorithmAesGcm decrypts nonce || ciphertext || tag
+	AlgorithmAesGcm = "aes-gcm"
+)
+
+const gcmNonceLen = 12
+
+// KeyProvider decrypts the subscriber key material (EncPermanentKey, EncOpcKey, EncTopcKey)
+// that the UDR stores encrypted at rest.
+type KeyProvider interface {
+	// Decrypt returns the plaintext of ciphertext protected with the parameters
+	// identified by the protectionParameterId of the authentication subscription
+	Decrypt(protectionParameterId string, ciphertext []byte) ([]byte, error)
+}
+
+// FileKey is one protection key of the file keystore
+type FileKey struct {
+	Id        string `yaml:"id"`
+	Algorithm string `yaml:"algorithm"`
+	Key       string `yaml:"key"`
+}
+
+type fileKeyStoreDocument struct {
+	Keys []FileKey `yaml:"keys"`
+}
+
+// FileKeyStore is an AES based KeyProvider whose keys are loaded from a YAML file.
+type FileKeyStore struct {
+	aeads  map[string]cipher.AEAD
+	blocks map[string]cipher.Block
+}
+
+var _ KeyProvider = &FileKeyStore{}
+
+// LoadFileKeyStore reads the keystore file, e.g.
+//
+//	keys:
+//	  - id: "1"
+//	    algorithm: aes-gcm
+//	    key: 000102030405060708090a0b0c0d0e0f
+func LoadFileKeyStore(path string) (*FileKeyStore, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read keystore: %w", err)
+	}
+
+	var doc fileKeyStoreDocument
+	if err = yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("parse keystore [%s]: %w", path, err)
+	}
+	return NewFileKeyStore(doc.Keys)
+}
+
+func NewFileKeyStore(keys []FileKey) (*FileKeyStore, error) {
+	ks := &FileKeyStore{
+		aeads:  make(map[string]cipher.AEAD),
+		blocks: make(map[string]cipher.Block),
+	}
+
+	for _, k := range keys {
+		if k.Id == "" {
+			return nil, fmt.Errorf("keystore key without id")
+		}
+		if ks.blocks[k.Id] != nil || ks.aeads[k.Id] != nil {
+			return nil, fmt.Errorf("duplicated keystore key id [%s]", k.Id)
+		}
+
+		raw, err := hex.DecodeString(k.Key)
+		if err != nil {
+			return nil, fmt.Errorf("keystore key [%s]: %w", k.Id, err)
+		}
+		block, err := aes.NewCipher(raw)
+		if err != nil {
+			return nil, fmt.Errorf("keystore key [%s]: %w", k.Id, err)
+		}
+
+		switch strings.ToLower(k.Algorithm) {
+		case AlgorithmAesEcb:
+			ks.blocks[k.Id] = block
+		case AlgorithmAesGcm:
+			aead, err := cipher.NewGCM(block)
+			if err != nil {
+				return nil, fmt.Errorf("keystore key [%s]: %w", k.Id, err)
+			}
+			ks.aeads[k.Id] = aead
+		default:
+			return nil, fmt.Errorf("keystore key [%s]: unsupported algorithm [%s]", k.Id, k.Algorithm)
+		}
+	}
+	return ks, nil
+}
+
+func (ks *FileKeyStore) Decrypt(protectionParameterId string, ciphertext []byte) ([]byte, error) {
+	if aead, ok := ks.aeads[protectionParameterId]; ok {
+		if len(ciphertext) < gcmNonceLen+aead.Overhead() {
+			return nil, fmt.Errorf("ciphertext is too short for %s", AlgorithmAesGcm)
+		}
+		return aead.Open(nil, ciphertext[:gcmNonceLen], ciphertext[gcmNonceLen:], nil)
+	}
+	if block, ok := ks.blocks[protectionParameterId]; ok {
+		return decryptEcb(block, ciphertext)
+	}
+	return nil, fmt.Errorf("no key for protection parameter [%s]", protectionParameterId)
+}
+
+func decryptEcb(block cipher.Block, ciphertext []byte) ([]byte, error) {
+	bs := block.BlockSize()
+	if len(ciphertext) == 0 || len(ciphertext)%bs != 0 {
+		return nil, fmt.Errorf("ciphertext length %d is not a multiple of the block size", len(ciphertext))
+	}
+	plaintext := make([]byte, len(ciphertext))
+	for i := 0; i < len(ciphertext); i += bs {
+		block.Decrypt(plaintext[i:i+bs], ciphertext[i:i+bs])
+	}
+	return plaintext, nil
+}
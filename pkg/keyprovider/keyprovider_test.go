@@ -0,0 +1,166 @@
+package keyprovider
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testKeyHex = "000102030405060708090a0b0c0d0e0f"
+	testK      = "8baf473f2f8fd09487cccbd7097c6862"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func encryptEcb(t *testing.T, key, plaintext []byte) []byte {
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	ciphertext := make([]byte, len(plaintext))
+	for i := 0; i < len(plaintext); i += block.BlockSize() {
+		block.Encrypt(ciphertext[i:i+block.BlockSize()], plaintext[i:i+block.BlockSize()])
+	}
+	return ciphertext
+}
+
+func encryptGcm(t *testing.T, key, plaintext []byte) []byte {
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	nonce := make([]byte, gcmNonceLen)
+	return aead.Seal(nonce, nonce, plaintext, nil)
+}
+
+func TestFileKeyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keystore.yaml")
+	content := fmt.Sprintf("keys:\n"+
+		"  - id: \"1\"\n    algorithm: aes-ecb\n    key: %s\n"+
+		"  - id: \"2\"\n    algorithm: AES-GCM\n    key: %s\n", testKeyHex, testKeyHex)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	ks, err := LoadFileKeyStore(path)
+	require.NoError(t, err)
+
+	key := mustHex(t, testKeyHex)
+	k := mustHex(t, testK)
+
+	plaintext, err := ks.Decrypt("1", encryptEcb(t, key, k))
+	require.NoError(t, err)
+	require.Equal(t, k, plaintext)
+
+	ciphertext := encryptGcm(t, key, k)
+	plaintext, err = ks.Decrypt("2", ciphertext)
+	require.NoError(t, err)
+	require.Equal(t, k, plaintext)
+
+	ciphertext[len(ciphertext)-1] ^= 0x01
+	_, err = ks.Decrypt("2", ciphertext)
+	require.Error(t, err)
+
+	_, err = ks.Decrypt("3", k)
+	require.ErrorContains(t, err, "no key for protection parameter [3]")
+}
+
+func TestNewFileKeyStoreInvalid(t *testing.T) {
+	testCases := []struct {
+		name string
+		keys []FileKey
+		err  string
+	}{
+		{
+			name: "duplicated id",
+			keys: []FileKey{
+				{Id: "1", Algorithm: AlgorithmAesEcb, Key: testKeyHex},
+				{Id: "1", Algorithm: AlgorithmAesGcm, Key: testKeyHex},
+			},
+			err: "duplicated keystore key id [1]",
+		},
+		{
+			name: "unsupported algorithm",
+			keys: []FileKey{{Id: "1", Algorithm: "des", Key: testKeyHex}},
+			err:  "unsupported algorithm [des]",
+		},
+		{
+			name: "invalid key length",
+			keys: []FileKey{{Id: "1", Algorithm: AlgorithmAesEcb, Key: "0001"}},
+			err:  "invalid key size",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewFileKeyStore(tc.keys)
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+// softToken stands in for a PKCS#11 token holding AES keys by label
+type softToken struct {
+	keys map[string][]byte
+}
+
+func (s *softToken) FindKey(label string) (Pkcs11ObjectHandle, error) {
+	if _, ok := s.keys[label]; !ok {
+		return 0, fmt.Errorf("CKR_KEY_HANDLE_INVALID")
+	}
+	return Pkcs11ObjectHandle(len(label)), nil
+}
+
+func (s *softToken) Decrypt(mechanism Pkcs11Mechanism, iv []byte, key Pkcs11ObjectHandle,
+	ciphertext []byte,
+) ([]byte, error) {
+	for _, k := range s.keys {
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, err
+		}
+		switch mechanism {
+		case MechanismAesEcb:
+			return decryptEcb(block, ciphertext)
+		case MechanismAesGcm:
+			aead, err := cipher.NewGCM(block)
+			if err != nil {
+				return nil, err
+			}
+			return aead.Open(nil, iv, ciphertext, nil)
+		}
+	}
+	return nil, fmt.Errorf("CKR_MECHANISM_INVALID")
+}
+
+func TestPkcs11Provider(t *testing.T) {
+	key := mustHex(t, testKeyHex)
+	k := mustHex(t, testK)
+	token := &softToken{keys: map[string][]byte{"udm-k": key}}
+
+	ecb, err := NewPkcs11Provider(token, MechanismAesEcb)
+	require.NoError(t, err)
+	plaintext, err := ecb.Decrypt("udm-k", encryptEcb(t, key, k))
+	require.NoError(t, err)
+	require.Equal(t, k, plaintext)
+
+	gcm, err := NewPkcs11Provider(token, MechanismAesGcm)
+	require.NoError(t, err)
+	plaintext, err = gcm.Decrypt("udm-k", encryptGcm(t, key, k))
+	require.NoError(t, err)
+	require.Equal(t, k, plaintext)
+
+	_, err = gcm.Decrypt("unknown", encryptGcm(t, key, k))
+	require.ErrorContains(t, err, "find token key [unknown]")
+
+	_, err = NewPkcs11Provider(token, 0x1082)
+	require.Error(t, err)
+}
@@ -0,0 +1,70 @@
+package keyprovider
+
+import (
+	"fmt"
+)
+
+// Pkcs11Mechanism is a CKM_* mechanism type of PKCS#11
+type Pkcs11Mechanism uint
+
+const (
+	MechanismAesEcb Pkcs11Mechanism = 0x1081 // CKM_AES_ECB
+	MechanismAesGcm Pkcs11Mechanism = 0x1087 // CKM_AES_GCM
+)
+
+// Pkcs11ObjectHandle is a CK_OBJECT_HANDLE of a token object
+type Pkcs11ObjectHandle uint
+
+// Pkcs11Session is the part of an open PKCS#11 session the Pkcs11Provider relies on.
+// It is implemented on top of a PKCS#11 binding, e.g. against a SoftHSM or a hardware token,
+// so that the protection keys never leave the token.
+type Pkcs11Session interface {
+	// FindKey returns the secret key object whose CKA_LABEL is label
+	// (C_FindObjectsInit, C_FindObjects, C_FindObjectsFinal)
+	FindKey(label string) (Pkcs11ObjectHandle, error)
+	// Decrypt runs C_DecryptInit and C_Decrypt; iv is the mechanism parameter, if any
+	Decrypt(mechanism Pkcs11Mechanism, iv []byte, key Pkcs11ObjectHandle, ciphertext []byte) ([]byte, error)
+}
+
+// Pkcs11Provider is a KeyProvider decrypting inside a PKCS#11 token.
+// The protectionParameterId of the subscription is the label of the token key.
+// The UDM has no PKCS#11 binding of its own, so the provider cannot be selected in the
+// configuration: the code embedding the UDM opens the session and sets the provider.
+type Pkcs11Provider struct {
+	session   Pkcs11Session
+	mechanism Pkcs11Mechanism
+}
+
+var _ KeyProvider = &Pkcs11Provider{}
+
+func NewPkcs11Provider(session Pkcs11Session, mechanism Pkcs11Mechanism) (*Pkcs11Provider, error) {
+	if session == nil {
+		return nil, fmt.Errorf("PKCS#11 session is nil")
+	}
+	switch mechanism {
+	case MechanismAesEcb, MechanismAesGcm:
+	default:
+		return nil, fmt.Errorf("unsupported PKCS#11 mechanism 0x%x", uint(mechanism))
+	}
+	return &Pkcs11Provider{session: session, mechanism: mechanism}, nil
+}
+
+func (p *Pkcs11Provider) Decrypt(protectionParameterId string, ciphertext []byte) ([]byte, error) {
+	key, err := p.session.FindKey(protectionParameterId)
+	if err != nil {
+		return nil, fmt.Errorf("find token key [%s]: %w", protectionParameterId, err)
+	}
+
+	var iv []byte
+	if p.mechanism == MechanismAesGcm {
+		if len(ciphertext) < gcmNonceLen {
+			return nil, fmt.Errorf("ciphertext is too short for CKM_AES_GCM")
+		}
+		iv, ciphertext = ciphertext[:gcmNonceLen], ciphertext[gcmNonceLen:]
+	}
+	plaintext, err := p.session.Decrypt(p.mechanism, iv, key, ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt with token key [%s]: %w", protectionParameterId, err)
+	}
+	return plaintext, nil
+}
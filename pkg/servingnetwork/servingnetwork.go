@@ -0,0 +1,98 @@
+// Package servingnetwork parses the serving network name of TS 24.501 9.12.1, used as input
+// to the key derivation of 5G AKA and EAP-AKA' (TS 33.501 6.1.1.4).
+package servingnetwork
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Name is a parsed serving network name
+type Name struct {
+	Mcc string
+	// Mnc is the MNC as it appears in the name, always 3 digits
+	Mnc string
+	// Nid is the network identifier of a stand-alone non-public network, empty for a PLMN
+	Nid string
+}
+
+// 5G:mnc<MNC>.mcc<MCC>.3gppnetwork.org with an optional :<NID> for an SNPN, the NID being
+// 11 hexadecimal digits (TS 23.003 12.7)
+var namePattern = regexp.MustCompile(`^5G:mnc([0-9]{3})\.mcc([0-9]{3})\.3gppnetwork\.org(?::([0-9A-Fa-f]{11}))?$`)
+
+// Parse validates and decodes a serving network name
+func Parse(servingNetworkName string) (*Name, error) {
+	m := namePattern.FindStringSubmatch(servingNetworkName)
+	if m == nil {
+		return nil, fmt.Errorf("malformed serving network name [%s]", servingNetworkName)
+	}
+	return &Name{Mcc: m[2], Mnc: m[1], Nid: strings.ToLower(m[3])}, nil
+}
+
+// IsSnpn tells whether the name identifies a stand-alone non-public network
+func (n *Name) IsSnpn() bool {
+	return n.Nid != ""
+}
+
+// MatchPlmn tells whether the name belongs to the PLMN mcc/mnc, mnc having 2 or 3 digits
+func (n *Name) MatchPlmn(mcc, mnc string) bool {
+	if n.Mcc != mcc {
+		return false
+	}
+	if len(mnc) == 2 {
+		mnc = "0" + mnc
+	}
+	return n.Mnc == mnc
+}
+
+// MatchNid tells whether the NID of the name is one of nids
+func (n *Name) MatchNid(nids []string) bool {
+	for _, nid := range nids {
+		if strings.EqualFold(nid, n.Nid) {
+			return true
+		}
+	}
+	return false
+}
+
+// Network is a PLMN, with the NIDs of the SNPNs it hosts that are covered as well
+type Network struct {
+	Mcc  string
+	Mnc  string
+	Nids []string
+}
+
+func (nw Network) match(name *Name) bool {
+	if !name.MatchPlmn(nw.Mcc, nw.Mnc) {
+		return false
+	}
+	return !name.IsSnpn() || name.MatchNid(nw.Nids)
+}
+
+// Policy lists the networks the home network serves its subscribers through: its own
+// networks and those it has a roaming agreement with.
+type Policy struct {
+	HomeNetworks      []Network
+	RoamingAgreements []Network
+}
+
+// IsHome tells whether name is one of the home networks
+func (p *Policy) IsHome(name *Name) bool {
+	_, ok := matchAny(p.HomeNetworks, name)
+	return ok
+}
+
+// RoamingAgreement returns the network of the roaming agreement covering name, if any
+func (p *Policy) RoamingAgreement(name *Name) (Network, bool) {
+	return matchAny(p.RoamingAgreements, name)
+}
+
+func matchAny(networks []Network, name *Name) (Network, bool) {
+	for _, nw := range networks {
+		if nw.match(name) {
+			return nw, true
+		}
+	}
+	return Network{}, false
+}
@@ -0,0 +1,77 @@
+package servingnetwork
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name    string
+		snn     string
+		expect  *Name
+		wantErr bool
+	}{
+		{
+			name:   "PLMN",
+			snn:    "5G:mnc093.mcc208.3gppnetwork.org",
+			expect: &Name{Mcc: "208", Mnc: "093"},
+		},
+		{
+			name:   "SNPN",
+			snn:    "5G:mnc001.mcc001.3gppnetwork.org:000007ED9D5",
+			expect: &Name{Mcc: "001", Mnc: "001", Nid: "000007ed9d5"},
+		},
+		{name: "free text", snn: "internet", wantErr: true},
+		{name: "2-digit MNC", snn: "5G:mnc93.mcc208.3gppnetwork.org", wantErr: true},
+		{name: "wrong prefix", snn: "4G:mnc093.mcc208.3gppnetwork.org", wantErr: true},
+		{name: "short NID", snn: "5G:mnc093.mcc208.3gppnetwork.org:0000", wantErr: true},
+		{name: "trailing data", snn: "5G:mnc093.mcc208.3gppnetwork.org.evil", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, err := Parse(tc.snn)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, name)
+		})
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	policy := Policy{
+		HomeNetworks: []Network{{Mcc: "208", Mnc: "93"}},
+		RoamingAgreements: []Network{
+			{Mcc: "001", Mnc: "001", Nids: []string{"000007ED9D5"}},
+			{Mcc: "466", Mnc: "92"},
+		},
+	}
+	parse := func(snn string) *Name {
+		name, err := Parse(snn)
+		require.NoError(t, err)
+		return name
+	}
+
+	hasAgreement := func(name *Name) bool {
+		_, ok := policy.RoamingAgreement(name)
+		return ok
+	}
+
+	require.True(t, policy.IsHome(parse("5G:mnc093.mcc208.3gppnetwork.org")))
+	require.False(t, policy.IsHome(parse("5G:mnc093.mcc208.3gppnetwork.org:000007ed9d5")))
+	require.False(t, policy.IsHome(parse("5G:mnc092.mcc466.3gppnetwork.org")))
+
+	nw, ok := policy.RoamingAgreement(parse("5G:mnc092.mcc466.3gppnetwork.org"))
+	require.True(t, ok)
+	require.Equal(t, "92", nw.Mnc)
+	require.True(t, hasAgreement(parse("5G:mnc001.mcc001.3gppnetwork.org")))
+	require.True(t, hasAgreement(parse("5G:mnc001.mcc001.3gppnetwork.org:000007ed9d5")))
+	require.False(t, hasAgreement(parse("5G:mnc001.mcc001.3gppnetwork.org:000007ed9d6")))
+	require.False(t, hasAgreement(parse("5G:mnc092.mcc466.3gppnetwork.org:000007ed9d5")))
+	require.False(t, hasAgreement(parse("5G:mnc001.mcc310.3gppnetwork.org")))
+}
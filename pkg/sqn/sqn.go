@@ -0,0 +1,180 @@
+// Package sqn implements the sequence number management of the AuC, following the
+// SEQ/IND array scheme of TS 33.102 Annex C.
+package sqn
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// Len is the length of SQN in bits
+	Len = 48
+	// HexLen is the length of SQN in hexadecimal digits
+	HexLen = Len / 4
+
+	// DefaultIndLength gives an array of a = 32 entries (TS 33.102 C.3.2)
+	DefaultIndLength = 5
+	// DefaultDelta is the recommended limit on the SEQ increase accepted by the USIM (TS 33.102 C.3.2)
+	DefaultDelta = 1 << 28
+	// MaxIndLength keeps enough SEQ bits for the counter to be usable
+	MaxIndLength = 16
+)
+
+// ErrExhausted is returned when SEQ reached its maximum value, a wrap-around would make
+// the USIM reject every further vector.
+var ErrExhausted = errors.New("SEQ reached its maximum value, the subscriber needs new credentials")
+
+// Scheme describes how the 48 bits of SQN are split into SEQ || IND and the limits
+// the USIM applies when verifying the freshness of SQN (TS 33.102 C.2).
+type Scheme struct {
+	// IndLength is the number of least significant bits of SQN holding IND
+	IndLength uint
+	// Delta is the largest SEQ increase the USIM accepts (wrap-around protection, C.2.2)
+	Delta uint64
+	// AgeLimit is the largest difference L between the highest accepted SEQ and the
+	// SEQ of an accepted vector (C.2.2); 0 disables the age limit
+	AgeLimit uint64
+	// ResyncStep, when not 0, makes a re-synchronization always restart at
+	// SQN_MS + ResyncStep instead of keeping SQN_HE or taking the SQN following SQN_MS
+	ResyncStep uint64
+}
+
+// DefaultScheme holds the values recommended in C.3.2, the base of a configured scheme
+var DefaultScheme = Scheme{
+	IndLength: DefaultIndLength,
+	Delta:     DefaultDelta,
+}
+
+// PlainResyncStep is the SQN increase of a re-synchronization without a configured scheme,
+// the IND of 32 plus one the UDM always used
+const PlainResyncStep = 33
+
+// PlainScheme is used when no scheme is configured: SQN is a counter incremented by 1 for
+// every vector, and a re-synchronization always restarts at SQN_MS + 33
+var PlainScheme = Scheme{
+	Delta:      1,
+	ResyncStep: PlainResyncStep,
+}
+
+func (s Scheme) Validate() error {
+	if s.IndLength > MaxIndLength {
+		return fmt.Errorf("IND length %d exceeds %d bits", s.IndLength, MaxIndLength)
+	}
+	if s.Delta == 0 || s.Delta > s.maxSeq() {
+		return fmt.Errorf("delta %d is out of range [1, %d]", s.Delta, s.maxSeq())
+	}
+	if s.AgeLimit > s.maxSeq() {
+		return fmt.Errorf("age limit %d exceeds %d", s.AgeLimit, s.maxSeq())
+	}
+	return nil
+}
+
+// ArraySize is the number a of IND values
+func (s Scheme) ArraySize() uint64 {
+	return 1 << s.IndLength
+}
+
+func (s Scheme) maxSeq() uint64 {
+	return 1<<(Len-s.IndLength) - 1
+}
+
+// Split returns the SEQ and IND parts of sqn
+func (s Scheme) Split(sqn uint64) (seq, ind uint64) {
+	return sqn >> s.IndLength, sqn & (s.ArraySize() - 1)
+}
+
+// Join builds SQN = SEQ || IND
+func (s Scheme) Join(seq, ind uint64) uint64 {
+	return seq<<s.IndLength | ind&(s.ArraySize()-1)
+}
+
+// Next returns the SQN following sqn: SEQ is incremented for every vector (C.1.1.2) and
+// IND is allocated round robin over the array (C.1.2).
+func (s Scheme) Next(sqn uint64) (uint64, error) {
+	seq, ind := s.Split(sqn)
+	if seq >= s.maxSeq() {
+		return 0, ErrExhausted
+	}
+	return s.Join(seq+1, (ind+1)%s.ArraySize()), nil
+}
+
+// Batch returns the SQNs of n vectors starting at sqnHE, the next SQN to be issued, and
+// the SQN_HE to store after the batch. The serving network may use the vectors of a batch
+// in any order, so a batch must not be wider than the age limit.
+func (s Scheme) Batch(sqnHE uint64, n int) (sqns []uint64, next uint64, err error) {
+	if n < 1 {
+		return nil, 0, fmt.Errorf("invalid number of vectors %d", n)
+	}
+	if s.AgeLimit != 0 && uint64(n-1) > s.AgeLimit {
+		return nil, 0, fmt.Errorf("%d vectors exceed the age limit %d", n, s.AgeLimit)
+	}
+
+	sqns = make([]uint64, n)
+	next = sqnHE
+	for i := range sqns {
+		sqns[i] = next
+		if next, err = s.Next(next); err != nil {
+			return nil, 0, err
+		}
+	}
+	return sqns, next, nil
+}
+
+// Resync returns the SQN_HE to continue from after the USIM reported SQN_MS, the highest
+// SQN it accepted (TS 33.102 6.3.5). Unless ResyncStep is set, SQN_HE is kept when the
+// USIM would accept it, otherwise it restarts right after SQN_MS.
+func (s Scheme) Resync(sqnHE, sqnMS uint64) (uint64, error) {
+	if s.ResyncStep != 0 {
+		if sqnMS > 1<<Len-1-s.ResyncStep {
+			return 0, ErrExhausted
+		}
+		return sqnMS + s.ResyncStep, nil
+	}
+	seqHE, _ := s.Split(sqnHE)
+	seqMS, _ := s.Split(sqnMS)
+	if seqHE > seqMS && seqHE-seqMS <= s.Delta {
+		return sqnHE, nil
+	}
+	return s.Next(sqnMS)
+}
+
+// Parse decodes a hexadecimal SQN, keeping its 48 least significant bits
+func Parse(sqnStr string) (uint64, error) {
+	if l := len(sqnStr); l < HexLen {
+		sqnStr = strings.Repeat("0", HexLen-l) + sqnStr
+	} else {
+		sqnStr = sqnStr[l-HexLen:]
+	}
+	b, err := hex.DecodeString(sqnStr)
+	if err != nil {
+		return 0, err
+	}
+	return FromBytes(b), nil
+}
+
+// Format encodes sqn as 12 hexadecimal digits
+func Format(sqn uint64) string {
+	return hex.EncodeToString(Bytes(sqn))
+}
+
+// FromBytes decodes the 6 octets of SQN
+func FromBytes(b []byte) uint64 {
+	var sqn uint64
+	for _, v := range b {
+		sqn = sqn<<8 | uint64(v)
+	}
+	return sqn & (1<<Len - 1)
+}
+
+// Bytes encodes sqn as 6 octets
+func Bytes(sqn uint64) []byte {
+	b := make([]byte, Len/8)
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i] = byte(sqn)
+		sqn >>= 8
+	}
+	return b
+}
@@ -0,0 +1,182 @@
+package sqn
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// usim verifies SQN like the USIM of TS 33.102 C.2, with an array of SEQ_MS per IND
+type usim struct {
+	scheme Scheme
+	seqMS  []uint64
+}
+
+func newUsim(s Scheme) *usim {
+	return &usim{scheme: s, seqMS: make([]uint64, s.ArraySize())}
+}
+
+// highest returns SQN_MS, the SEQ_MS of the array with its IND, as sent in AUTS
+func (u *usim) highest() uint64 {
+	var seq, ind uint64
+	for i, v := range u.seqMS {
+		if v > seq {
+			seq, ind = v, uint64(i)
+		}
+	}
+	return u.scheme.Join(seq, ind)
+}
+
+// verify accepts sqn or returns the SQN_MS of a synchronisation failure
+func (u *usim) verify(sqn uint64) (sqnMS uint64, ok bool) {
+	seq, ind := u.scheme.Split(sqn)
+	highestSeq, _ := u.scheme.Split(u.highest())
+	switch {
+	case seq <= u.seqMS[ind]:
+	case seq > highestSeq && seq-highestSeq > u.scheme.Delta:
+	case u.scheme.AgeLimit != 0 && highestSeq > seq && highestSeq-seq > u.scheme.AgeLimit:
+	default:
+		u.seqMS[ind] = seq
+		return 0, true
+	}
+	return u.highest(), false
+}
+
+func TestSplitJoin(t *testing.T) {
+	s := DefaultScheme
+	sqn, err := Parse("000000000023")
+	require.NoError(t, err)
+
+	seq, ind := s.Split(sqn)
+	require.Equal(t, uint64(1), seq)
+	require.Equal(t, uint64(3), ind)
+	require.Equal(t, sqn, s.Join(seq, ind))
+
+	next, err := s.Next(sqn)
+	require.NoError(t, err)
+	require.Equal(t, "000000000044", Format(next))
+
+	// IND wraps around the array while SEQ keeps increasing
+	next, err = s.Next(s.Join(7, 31))
+	require.NoError(t, err)
+	require.Equal(t, s.Join(8, 0), next)
+
+	require.Equal(t, sqn, FromBytes(Bytes(sqn)))
+	sqn, err = Parse("1000000000023")
+	require.NoError(t, err)
+	require.Equal(t, "000000000023", Format(sqn))
+}
+
+func TestBatch(t *testing.T) {
+	s := DefaultScheme
+	sqns, next, err := s.Batch(0x23, 3)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{0x23, 0x44, 0x65}, sqns)
+	require.Equal(t, uint64(0x86), next)
+
+	s.AgeLimit = 2
+	_, _, err = s.Batch(0x23, 3)
+	require.NoError(t, err)
+	_, _, err = s.Batch(0x23, 4)
+	require.ErrorContains(t, err, "age limit")
+}
+
+func TestPlainScheme(t *testing.T) {
+	s := PlainScheme
+	require.NoError(t, s.Validate())
+
+	sqns, next, err := s.Batch(0x23, 3)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{0x23, 0x24, 0x25}, sqns)
+	require.Equal(t, uint64(0x26), next)
+
+	// a re-synchronization restarts at SQN_MS + 33, ahead of or behind SQN_HE
+	for _, sqnHE := range []uint64{0x10, 0x31, 0x40} {
+		resynced, err := s.Resync(sqnHE, 0x30)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0x51), resynced)
+	}
+	_, err = s.Resync(0x10, 1<<Len-PlainResyncStep)
+	require.ErrorIs(t, err, ErrExhausted)
+}
+
+func TestWrapAroundProtection(t *testing.T) {
+	s := Scheme{IndLength: 5, Delta: DefaultDelta}
+	last := s.Join(s.maxSeq(), 4)
+	_, err := s.Next(last)
+	require.ErrorIs(t, err, ErrExhausted)
+	_, _, err = s.Batch(s.Join(s.maxSeq()-1, 0), 3)
+	require.ErrorIs(t, err, ErrExhausted)
+
+	// the USIM rejects a SEQ jumping further than delta
+	s.Delta = 16
+	u := newUsim(s)
+	_, ok := u.verify(s.Join(17, 0))
+	require.False(t, ok)
+	_, ok = u.verify(s.Join(16, 0))
+	require.True(t, ok)
+}
+
+func TestResyncAcrossIndSlots(t *testing.T) {
+	s := Scheme{IndLength: 2, Delta: 64, AgeLimit: 8}
+	u := newUsim(s)
+
+	// two serving networks fetch batches, the vectors are used out of order
+	batchA, sqnHE, err := s.Batch(s.Join(1, 0), 4)
+	require.NoError(t, err)
+	batchB, sqnHE, err := s.Batch(sqnHE, 4)
+	require.NoError(t, err)
+	for _, sqn := range []uint64{batchB[0], batchA[1], batchB[3], batchA[2]} {
+		_, ok := u.verify(sqn)
+		require.True(t, ok, "SQN %s", Format(sqn))
+	}
+
+	// a vector whose IND slot already holds a higher SEQ is rejected
+	sqnMS, ok := u.verify(batchA[0])
+	require.False(t, ok)
+	require.Equal(t, batchB[3], sqnMS)
+
+	// SQN_HE is still acceptable, the HE keeps it
+	resynced, err := s.Resync(sqnHE, sqnMS)
+	require.NoError(t, err)
+	require.Equal(t, sqnHE, resynced)
+	_, ok = u.verify(resynced)
+	require.True(t, ok)
+
+	// a vector older than the age limit is rejected even though its slot is fresh
+	u = newUsim(s)
+	_, ok = u.verify(s.Join(20, 1))
+	require.True(t, ok)
+	sqnMS, ok = u.verify(s.Join(11, 2))
+	require.False(t, ok)
+	require.Equal(t, s.Join(20, 1), sqnMS)
+	_, ok = u.verify(s.Join(12, 2))
+	require.True(t, ok)
+
+	// the HE fell behind the USIM (e.g. restored from a backup), it restarts after SQN_MS
+	resynced, err = s.Resync(s.Join(5, 3), sqnMS)
+	require.NoError(t, err)
+	require.Equal(t, s.Join(21, 2), resynced)
+	_, ok = u.verify(resynced)
+	require.True(t, ok)
+
+	// the HE is ahead by more than delta, the USIM would keep rejecting SQN_HE
+	resynced, err = s.Resync(s.Join(200, 0), sqnMS)
+	require.NoError(t, err)
+	require.Equal(t, s.Join(21, 2), resynced)
+
+	// a batch spread over the whole array is accepted in any order after the resync
+	batch, _, err := s.Batch(s.Join(22, 3), int(s.ArraySize()))
+	require.NoError(t, err)
+	for i := len(batch) - 1; i >= 0; i-- {
+		_, ok = u.verify(batch[i])
+		require.True(t, ok, "SQN %s", Format(batch[i]))
+	}
+}
+
+func TestSchemeValidate(t *testing.T) {
+	require.NoError(t, DefaultScheme.Validate())
+	require.Error(t, Scheme{IndLength: 17, Delta: 1}.Validate())
+	require.Error(t, Scheme{IndLength: 5}.Validate())
+	require.Error(t, Scheme{IndLength: 5, Delta: 1, AgeLimit: 1 << 44}.Validate())
+}
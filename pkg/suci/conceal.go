@@ -0,0 +1,174 @@
+package suci
+
+import (
+	"crypto/ecdh"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	imsiSupiRegex        = regexp.MustCompile(`^imsi-(\d{5,15})$`)
+	routingIndicatorOnly = regexp.MustCompile(`^\d{1,4}$`)
+)
+
+// FromSupi conceals supi like a UE provisioned with the home network public key of profile
+// (TS 33.501 C.3), generating a fresh ECIES ephemeral key pair for every SUCI. A nil profile
+// selects the null scheme. mncLen, 2 or 3, splits the MCC and MNC of an IMSI; the realm of a
+// NAI is its home network identifier.
+func FromSupi(supi string, mncLen int, routingIndicator string, profile *SuciProfile) (string, error) {
+	if !routingIndicatorOnly.MatchString(routingIndicator) {
+		return "", fmt.Errorf("invalid routing indicator [%s], should be 1 to 4 digits", routingIndicator)
+	}
+
+	var supiType, homeNetworkId string
+	var schemeInput []byte
+	if m := imsiSupiRegex.FindStringSubmatch(supi); m != nil {
+		if mncLen != 2 && mncLen != 3 {
+			return "", fmt.Errorf("invalid MNC length %d", mncLen)
+		}
+		imsi := m[1]
+		if len(imsi) <= 3+mncLen {
+			return "", fmt.Errorf("IMSI [%s] has no MSIN", imsi)
+		}
+		supiType = SupiTypeIMSI
+		homeNetworkId = imsi[:3] + "-" + imsi[3:3+mncLen]
+		schemeInput = []byte(imsi[3+mncLen:])
+	} else if strings.HasPrefix(supi, PrefixNAI) {
+		username, realm, ok := strings.Cut(strings.TrimPrefix(supi, PrefixNAI), "@")
+		if !ok || username == "" || realm == "" {
+			return "", fmt.Errorf("invalid NAI [%s]", supi)
+		}
+		supiType = SupiTypeNAI
+		homeNetworkId = realm
+		schemeInput = []byte(username)
+	} else {
+		return "", fmt.Errorf("unsupported SUPI [%s]", supi)
+	}
+
+	prefix := strings.Join([]string{PrefixSUCI, supiType, homeNetworkId, routingIndicator}, "-")
+	if profile == nil {
+		return prefix + "-" + NullScheme + "-0-" + string(schemeInput), nil
+	}
+
+	if supiType == SupiTypeIMSI {
+		// the MSIN is BCD coded with swapped nibbles, an odd length is padded with 'f'
+		msin := string(schemeInput)
+		if len(msin)%2 == 1 {
+			msin += "f"
+		}
+		bcd, err := hex.DecodeString(msin)
+		if err != nil {
+			return "", fmt.Errorf("invalid MSIN: %w", err)
+		}
+		schemeInput = swapNibbles(bcd)
+	}
+
+	var schemeOutput []byte
+	var err error
+	switch strings.ToUpper(profile.ProtectionScheme) {
+	case ProfileAScheme:
+		schemeOutput, err = concealProfileA(schemeInput, profile.PublicKey)
+	case ProfileBScheme:
+		schemeOutput, err = concealProfileB(schemeInput, profile.PublicKey)
+	default:
+		return "", fmt.Errorf("%w: protect Scheme (%s) has no encryption", ErrorUnsupportedScheme,
+			profile.ProtectionScheme)
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{
+		prefix, strings.ToUpper(profile.ProtectionScheme), strconv.Itoa(profile.KeyId),
+		hex.EncodeToString(schemeOutput),
+	}, "-"), nil
+}
+
+func concealProfileA(schemeInput []byte, publicKeyHex string) ([]byte, error) {
+	hnPubBytes, err := hex.DecodeString(publicKeyHex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode X25519 public key: %w", err)
+	}
+	hnPub, err := ecdh.X25519().NewPublicKey(hnPubBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse X25519 public key: %w", err)
+	}
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate X25519 ephemeral key: %w", err)
+	}
+	sharedKey, err := ephemeral.ECDH(hnPub)
+	if err != nil {
+		return nil, err
+	}
+
+	ephemeralPub := ephemeral.PublicKey().Bytes()
+	sealed, err := encryptWithKdf(sharedKey, ephemeralPub, schemeInput,
+		ProfileAEncKeyLen, ProfileAMacKeyLen, ProfileAHashLen, ProfileAIcbLen, ProfileAMacLen)
+	if err != nil {
+		return nil, err
+	}
+	return append(ephemeralPub, sealed...), nil
+}
+
+func concealProfileB(schemeInput []byte, publicKeyHex string) ([]byte, error) {
+	hnPubBytes, err := hex.DecodeString(publicKeyHex)
+	if err != nil || len(hnPubBytes) == 0 {
+		return nil, fmt.Errorf("failed to decode P-256 public key: %v", err)
+	}
+	if hnPubBytes[0] == 0x02 || hnPubBytes[0] == 0x03 {
+		x, y := elliptic.UnmarshalCompressed(elliptic.P256(), hnPubBytes)
+		if x == nil || y == nil {
+			return nil, fmt.Errorf("failed to uncompress public key")
+		}
+		hnPubBytes = elliptic.Marshal(elliptic.P256(), x, y)
+	}
+	hnPub, err := ecdh.P256().NewPublicKey(hnPubBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create P-256 public key: %w", err)
+	}
+	ephemeral, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate P-256 ephemeral key: %w", err)
+	}
+	sharedKey, err := ephemeral.ECDH(hnPub)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute ECDH: %w", err)
+	}
+
+	// the ephemeral public key is sent and fed to the KDF in compressed form
+	ephemeralPub, err := encodePublicKey(ephemeral.PublicKey(), true)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := encryptWithKdf(sharedKey, ephemeralPub, schemeInput,
+		ProfileBEncKeyLen, ProfileBMacKeyLen, ProfileBHashLen, ProfileBIcbLen, ProfileBMacLen)
+	if err != nil {
+		return nil, err
+	}
+	return append(ephemeralPub, sealed...), nil
+}
+
+// encryptWithKdf is the counterpart of decryptWithKdf, it returns ciphertext || MAC tag
+func encryptWithKdf(sharedKey, kdfPubKey, plainText []byte,
+	encKeyLen, macKeyLen, hashLen, icbLen, macLen int,
+) ([]byte, error) {
+	kdfKey := AnsiX963KDF(sharedKey, kdfPubKey, encKeyLen, macKeyLen, hashLen)
+	encKey := kdfKey[:encKeyLen]
+	icb := kdfKey[encKeyLen : encKeyLen+icbLen]
+	macKey := kdfKey[len(kdfKey)-macKeyLen:]
+
+	cipherText, err := Aes128ctr(plainText, encKey, icb)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := HmacSha256(cipherText, macKey, macLen)
+	if err != nil {
+		return nil, err
+	}
+	return append(cipherText, mac...), nil
+}
@@ -0,0 +1,99 @@
+package suci
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/mlkem"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/free5gc/udm/internal/logger"
+)
+
+// AlgorithmX25519MlKem768 is an operator-specific hybrid protection scheme combining the
+// X25519 exchange of Profile A with ML-KEM-768 (FIPS 203), so that the SUPI stays concealed
+// unless both are broken. The keys and the scheme output are:
+//
+//	PrivateKey:    X25519 private key (32 octets) || ML-KEM-768 seed (64 octets)
+//	PublicKey:     X25519 public key (32 octets) || ML-KEM-768 encapsulation key (1184 octets)
+//	scheme output: ephemeral X25519 public key (32 octets) || ML-KEM-768 ciphertext (1088 octets) ||
+//	               ciphertext || MAC tag (8 octets)
+//
+// The ANSI X9.63 KDF of Profile A runs over the ML-KEM shared secret || the X25519 shared secret,
+// with the ephemeral public key || the ML-KEM ciphertext as shared info.
+const AlgorithmX25519MlKem768 = "x25519-mlkem768"
+
+const (
+	hybridX25519KeyLen  = 32
+	hybridPrivateKeyLen = hybridX25519KeyLen + mlkem.SeedSize
+	hybridPublicKeyLen  = hybridX25519KeyLen + mlkem.EncapsulationKeySize768
+)
+
+type hybridMlKemDecryptor struct{}
+
+func (hybridMlKemDecryptor) Decrypt(schemeOutput []byte, profile SuciProfile) ([]byte, error) {
+	logger.SuciLog.Infoln("SuciToSupi X25519 ML-KEM-768 hybrid")
+
+	x25519Priv, mlkemKey, err := parseHybridPrivateKey(profile.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	const headerLen = hybridX25519KeyLen + mlkem.CiphertextSize768
+	if len(schemeOutput) < headerLen+ProfileAMacLen {
+		return nil, fmt.Errorf("suci input too short")
+	}
+	peerPubKey := schemeOutput[:hybridX25519KeyLen]
+	kemCipherText := schemeOutput[hybridX25519KeyLen:headerLen]
+	cipherText := schemeOutput[headerLen : len(schemeOutput)-ProfileAMacLen]
+	providedMac := schemeOutput[len(schemeOutput)-ProfileAMacLen:]
+
+	kemSharedKey, err := mlkemKey.Decapsulate(kemCipherText)
+	if err != nil {
+		return nil, fmt.Errorf("ML-KEM-768 decapsulation failed: %w", err)
+	}
+	ecdhSharedKey, err := ecdhX25519(hex.EncodeToString(x25519Priv), peerPubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedKey := append(kemSharedKey, ecdhSharedKey...)
+	sharedInfo := schemeOutput[:headerLen]
+	return decryptWithKdf(sharedKey, sharedInfo, cipherText, providedMac,
+		ProfileAEncKeyLen, ProfileAMacKeyLen, ProfileAHashLen, ProfileAIcbLen, ProfileAMacLen)
+}
+
+func (hybridMlKemDecryptor) ValidateProfile(profile SuciProfile) error {
+	x25519Priv, mlkemKey, err := parseHybridPrivateKey(profile.PrivateKey)
+	if err != nil {
+		return err
+	}
+	publicKey, err := hex.DecodeString(profile.PublicKey)
+	if err != nil || len(publicKey) != hybridPublicKeyLen {
+		return fmt.Errorf("invalid PublicKey, should be %d hexadecimal digits for %s",
+			2*hybridPublicKeyLen, AlgorithmX25519MlKem768)
+	}
+
+	priv, err := ecdh.X25519().NewPrivateKey(x25519Priv)
+	if err != nil {
+		return fmt.Errorf("failed to parse X25519 private key: %w", err)
+	}
+	expected := append(priv.PublicKey().Bytes(), mlkemKey.EncapsulationKey().Bytes()...)
+	if !bytes.Equal(expected, publicKey) {
+		return ErrorKeyPairMismatch
+	}
+	return nil
+}
+
+func parseHybridPrivateKey(privateKeyHex string) ([]byte, *mlkem.DecapsulationKey768, error) {
+	privateKey, err := hex.DecodeString(privateKeyHex)
+	if err != nil || len(privateKey) != hybridPrivateKeyLen {
+		return nil, nil, fmt.Errorf("invalid PrivateKey, should be %d hexadecimal digits for %s",
+			2*hybridPrivateKeyLen, AlgorithmX25519MlKem768)
+	}
+	mlkemKey, err := mlkem.NewDecapsulationKey768(privateKey[hybridX25519KeyLen:])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse ML-KEM-768 seed: %w", err)
+	}
+	return privateKey[:hybridX25519KeyLen], mlkemKey, nil
+}
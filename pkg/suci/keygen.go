@@ -0,0 +1,95 @@
+package suci
+
+import (
+	"crypto/ecdh"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// GenerateProfile generates a home network key pair for the SUCI profile keyId of protectionScheme,
+// X25519 for Profile A and P-256 for Profile B. compressed selects the compressed encoding of a
+// Profile B public key; X25519 public keys have a single encoding.
+func GenerateProfile(keyId int, protectionScheme string, compressed bool) (SuciProfile, error) {
+	curve, err := schemeCurve(protectionScheme)
+	if err != nil {
+		return SuciProfile{}, err
+	}
+	if keyId < MinKeyId || keyId > MaxKeyId {
+		return SuciProfile{}, fmt.Errorf("invalid KeyId: %d, should be in range [%d, %d]", keyId, MinKeyId, MaxKeyId)
+	}
+
+	priv, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return SuciProfile{}, fmt.Errorf("failed to generate key pair: %w", err)
+	}
+	pub, err := encodePublicKey(priv.PublicKey(), compressed)
+	if err != nil {
+		return SuciProfile{}, err
+	}
+	return SuciProfile{
+		KeyId:            keyId,
+		ProtectionScheme: strings.ToUpper(protectionScheme),
+		PrivateKey:       hex.EncodeToString(priv.Bytes()),
+		PublicKey:        hex.EncodeToString(pub),
+	}, nil
+}
+
+func schemeCurve(protectionScheme string) (ecdh.Curve, error) {
+	switch strings.ToUpper(protectionScheme) {
+	case ProfileAScheme:
+		return ecdh.X25519(), nil
+	case ProfileBScheme:
+		return ecdh.P256(), nil
+	default:
+		return nil, fmt.Errorf("%w: protect Scheme (%s) has no elliptic curve key pair",
+			ErrorUnsupportedScheme, protectionScheme)
+	}
+}
+
+// encodePublicKey returns the X25519 public key as is, and the P-256 public key in the
+// uncompressed or the compressed form of SEC 1
+func encodePublicKey(pub *ecdh.PublicKey, compressed bool) ([]byte, error) {
+	if pub.Curve() != ecdh.P256() || !compressed {
+		return pub.Bytes(), nil
+	}
+	x, y := elliptic.Unmarshal(elliptic.P256(), pub.Bytes())
+	if x == nil || y == nil {
+		return nil, ErrorPublicKeyUnmarshalling
+	}
+	return elliptic.MarshalCompressed(elliptic.P256(), x, y), nil
+}
+
+// checkEcKeyPair checks that the public key of profile is the one of its private key, in
+// either encoding for Profile B
+func checkEcKeyPair(profile SuciProfile) error {
+	curve, err := schemeCurve(profile.ProtectionScheme)
+	if err != nil {
+		return err
+	}
+	privBytes, err := hex.DecodeString(profile.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("failed to decode PrivateKey: %w", err)
+	}
+	priv, err := curve.NewPrivateKey(privBytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse PrivateKey: %w", err)
+	}
+	pubBytes, err := hex.DecodeString(profile.PublicKey)
+	if err != nil {
+		return fmt.Errorf("failed to decode PublicKey: %w", err)
+	}
+
+	// a compressed P-256 public key starts with 0x02 or 0x03, an uncompressed one with 0x04
+	compressed := curve == ecdh.P256() && len(pubBytes) > 0 && pubBytes[0] != 0x04
+	expected, err := encodePublicKey(priv.PublicKey(), compressed)
+	if err != nil {
+		return err
+	}
+	if hex.EncodeToString(expected) != strings.ToLower(profile.PublicKey) {
+		return ErrorKeyPairMismatch
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package suci
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/asaskevich/govalidator"
+)
+
+// Decryptor de-conceals the scheme output of a protection scheme (TS 33.501 C.3).
+// Decrypt returns the scheme input, i.e. the BCD coded MSIN of an IMSI or the
+// username of a NAI, which ToSupi turns into the SUPI.
+type Decryptor interface {
+	Decrypt(schemeOutput []byte, profile SuciProfile) ([]byte, error)
+	// ValidateProfile checks the keys of a profile using the scheme when the configuration is loaded
+	ValidateProfile(profile SuciProfile) error
+}
+
+var (
+	registryLock sync.RWMutex
+	// protection scheme ID as key, in upper case
+	schemes = map[string]Decryptor{
+		ProfileAScheme: profileADecryptor{},
+		ProfileBScheme: profileBDecryptor{},
+	}
+	// algorithm name as key, in lower case
+	algorithms = map[string]Decryptor{
+		AlgorithmX25519MlKem768: hybridMlKemDecryptor{},
+	}
+)
+
+// RegisterScheme makes d the decryptor of the protection scheme schemeId, a hexadecimal digit.
+// The null scheme and the schemes already registered cannot be replaced.
+func RegisterScheme(schemeId string, d Decryptor) error {
+	schemeId = strings.ToUpper(schemeId)
+	if !govalidator.StringMatches(schemeId, "^[1-9A-F]$") {
+		return fmt.Errorf("invalid protection scheme ID [%s]", schemeId)
+	}
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if _, ok := schemes[schemeId]; ok {
+		return fmt.Errorf("protection scheme [%s] is already registered", schemeId)
+	}
+	schemes[schemeId] = d
+	return nil
+}
+
+// RegisterAlgorithm makes d available to the profiles naming algorithm, so that an operator
+// can run it under any of its protection scheme IDs.
+func RegisterAlgorithm(algorithm string, d Decryptor) error {
+	algorithm = strings.ToLower(algorithm)
+	if algorithm == "" {
+		return fmt.Errorf("empty protection scheme algorithm name")
+	}
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	if _, ok := algorithms[algorithm]; ok {
+		return fmt.Errorf("protection scheme algorithm [%s] is already registered", algorithm)
+	}
+	algorithms[algorithm] = d
+	return nil
+}
+
+func (p SuciProfile) decryptor() (Decryptor, error) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	if p.Algorithm != "" {
+		if d, ok := algorithms[strings.ToLower(p.Algorithm)]; ok {
+			return d, nil
+		}
+		return nil, fmt.Errorf("%w: algorithm (%s) is not supported", ErrorUnsupportedScheme, p.Algorithm)
+	}
+	if d, ok := schemes[strings.ToUpper(p.ProtectionScheme)]; ok {
+		return d, nil
+	}
+	return nil, fmt.Errorf("%w: protect Scheme (%s) is not supported", ErrorUnsupportedScheme, p.ProtectionScheme)
+}
+
+// supportsScheme tells whether a decryptor is registered for schemeId or one of the profiles
+// runs a named algorithm under it
+func (t ProfileTable) supportsScheme(schemeId string) bool {
+	registryLock.RLock()
+	_, ok := schemes[strings.ToUpper(schemeId)]
+	registryLock.RUnlock()
+	if ok {
+		return true
+	}
+	for _, profile := range t {
+		if profile.Algorithm != "" && strings.EqualFold(profile.ProtectionScheme, schemeId) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateProfile checks that the protection scheme of profile is supported and that its keys
+// suit the scheme
+func ValidateProfile(profile SuciProfile) error {
+	if profile.ProtectionScheme == NullScheme {
+		return nil
+	}
+	d, err := profile.decryptor()
+	if err != nil {
+		return err
+	}
+	return d.ValidateProfile(profile)
+}
+
+type profileADecryptor struct{}
+
+func (profileADecryptor) Decrypt(schemeOutput []byte, profile SuciProfile) ([]byte, error) {
+	return profileA(schemeOutput, profile.PrivateKey)
+}
+
+func (profileADecryptor) ValidateProfile(profile SuciProfile) error {
+	return validateEcKeys(profile)
+}
+
+type profileBDecryptor struct{}
+
+func (profileBDecryptor) Decrypt(schemeOutput []byte, profile SuciProfile) ([]byte, error) {
+	return profileB(schemeOutput, profile.PrivateKey)
+}
+
+func (profileBDecryptor) ValidateProfile(profile SuciProfile) error {
+	return validateEcKeys(profile)
+}
+
+func validateEcKeys(profile SuciProfile) error {
+	if !govalidator.StringMatches(profile.PrivateKey, "^[A-Fa-f0-9]{64}$") {
+		return fmt.Errorf("invalid PrivateKey: %s, should be 64 hexadecimal digits", profile.PrivateKey)
+	}
+	if !govalidator.StringMatches(profile.PublicKey, "^[A-Fa-f0-9]{64,130}$") {
+		return fmt.Errorf("invalid PublicKey: %s, should be 64(profile A), 66(profile B, compressed),"+
+			"or 130(profile B, uncompressed) hexadecimal digits", profile.PublicKey)
+	}
+	return checkEcKeyPair(profile)
+}
@@ -15,17 +15,20 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/free5gc/udm/internal/logger"
 )
 
 // suci-0(SUPI type: IMSI)-mcc-mnc-routingIndicator-protectionScheme-homeNetworkPublicKeyID-schemeOutput.
-// TODO: suci-1(SUPI type: NAI)-homeNetworkID-routingIndicator-protectionScheme-homeNetworkPublicKeyID-schemeOutput.
+// suci-1(SUPI type: NAI)-homeNetworkID-routingIndicator-protectionScheme-homeNetworkPublicKeyID-schemeOutput.
 
 const (
 	PrefixIMSI     = "imsi-"
+	PrefixNAI      = "nai-"
 	PrefixSUCI     = "suci"
 	SupiTypeIMSI   = "0"
+	SupiTypeNAI    = "1"
 	NullScheme     = "0"
 	ProfileAScheme = "1"
 	ProfileBScheme = "2"
@@ -43,8 +46,11 @@ var (
 
 	// The Home Network Identifier consists of a string of
 	// characters with a variable length representing a domain name
-	// as specified in Section 2.2 of RFC 7542
-	naiTypeRegex = "(?P<naiType>1-.*)"
+	// as specified in Section 2.2 of RFC 7542. It is matched lazily: the null scheme output of a
+	// NAI may contain '-' as well, and the SUCI splits at the first valid position.
+	homeNetworkIdRegex = `(?P<home_network_id>[A-Za-z0-9](?:[A-Za-z0-9-]*?[A-Za-z0-9])??` +
+		`(?:\.[A-Za-z0-9](?:[A-Za-z0-9-]*?[A-Za-z0-9])??)*?)`
+	naiTypeRegex = fmt.Sprintf("(?P<naiType>1-%s)", homeNetworkIdRegex)
 
 	// SUPI type; 0 = IMSI, 1 = NAI (for n3gpp)
 	supiTypeRegex = fmt.Sprintf("(?P<supi_type>%s|%s)",
@@ -53,12 +59,15 @@ var (
 
 	// Routing Indicator, used by the AUSF to find the appropriate UDM when SUCI is encrypted 1-4 digits
 	routingIndicatorRegex = `(?P<routing_indicator>\d{1,4})`
-	// Protection Scheme ID; 0 = NULL Scheme (unencrypted), 1 = Profile A, 2 = Profile B
-	protectionSchemeRegex = `(?P<protection_scheme_id>(?:[0-2]))`
+	// Protection Scheme ID; 0 = NULL Scheme (unencrypted), 1 = Profile A, 2 = Profile B,
+	// other values are decrypted by the scheme registered for them (see RegisterScheme)
+	protectionSchemeRegex = `(?P<protection_scheme_id>(?:[0-9A-Fa-f]))`
 	// Public Key ID; 1-255
 	publicKeyIDRegex = `(?P<public_key_id>(?:\d{1,2}|1\d{2}|2[0-4]\d|25[0-5]))`
-	// Scheme Output; unbounded hex string (safe from ReDoS due to bounded length of SUCI)
-	schemeOutputRegex = `(?P<scheme_output>[A-Fa-f0-9]+)`
+	// Scheme Output; a hex string, or the NAI username with the null scheme (safe from ReDoS due
+	// to bounded length of SUCI)
+	schemeOutputRegex = `(?P<scheme_output>[^@\s]+)`
+	hexRegex          = regexp.MustCompile(`^[A-Fa-f0-9]+$`)
 	// Subscription Concealed Identifier (SUCI) Encrypted SUPI as sent by the UE to the AMF; 3GPP TS 29.503 - Annex C
 	suciRegex = regexp.MustCompile(fmt.Sprintf("^suci-%s-%s-%s-%s-%s$",
 		supiTypeRegex,
@@ -80,29 +89,96 @@ type Suci struct {
 	SchemeOutput     string // hex string
 }
 
+// RoutingIndicator returns the routing indicator of a SUCI, ok is false when input is not a SUCI
+func RoutingIndicator(input string) (routingIndicator string, ok bool) {
+	parsed := parseSuci(input)
+	if parsed == nil {
+		return "", false
+	}
+	return parsed.RoutingIndicator, true
+}
+
 func parseSuci(input string) *Suci {
 	matches := suciRegex.FindStringSubmatch(input)
-	if matches == nil || len(matches) != 10 {
+	if matches == nil {
 		return nil
 	}
+	group := func(name string) string {
+		return matches[suciRegex.SubexpIndex(name)]
+	}
 
-	// The indices correspond to the order of the regex groups in the pattern
-	return &Suci{
-		SupiType:         matches[1], // First capture group
-		Mcc:              matches[3], // Third capture group
-		Mnc:              matches[4], // Fourth capture group
-		HomeNetworkId:    matches[5], // Fifth capture group
-		RoutingIndicator: matches[6], // Sixth capture group
-		ProtectionScheme: matches[7], // Seventh capture group
-		PublicKeyID:      matches[8], // Eighth capture group
-		SchemeOutput:     matches[9], // Ninth capture group
+	parsed := &Suci{
+		SupiType:         SupiTypeIMSI,
+		Mcc:              group("mcc"),
+		Mnc:              group("mnc"),
+		HomeNetworkId:    group("home_network_id"),
+		RoutingIndicator: group("routing_indicator"),
+		ProtectionScheme: group("protection_scheme_id"),
+		PublicKeyID:      group("public_key_id"),
+		SchemeOutput:     group("scheme_output"),
 	}
+	if group("naiType") != "" {
+		parsed.SupiType = SupiTypeNAI
+	}
+	// only the null scheme of a NAI carries the username in clear, other scheme outputs are hex
+	if (parsed.SupiType != SupiTypeNAI || parsed.ProtectionScheme != NullScheme) &&
+		!hexRegex.MatchString(parsed.SchemeOutput) {
+		return nil
+	}
+	return parsed
+}
+
+// IsValidSuci tells whether input is a well-formed SUCI of either SUPI type, with a home
+// network public key ID of 1-255
+func IsValidSuci(input string) bool {
+	return parseSuci(input) != nil
+}
+
+// IsNaiSuci tells whether input is a well-formed SUCI of a NAI SUPI (suci-1)
+func IsNaiSuci(input string) bool {
+	parsed := parseSuci(input)
+	return parsed != nil && parsed.SupiType == SupiTypeNAI
 }
 
 type SuciProfile struct {
+	// KeyId is the home network public key identifier (1-255) the UE puts in the SUCI. A profile
+	// without KeyId takes its position in the list, as configurations did before key IDs.
+	KeyId            int    `yaml:"KeyId,omitempty"`
 	ProtectionScheme string `yaml:"ProtectionScheme,omitempty"`
-	PrivateKey       string `yaml:"PrivateKey,omitempty"`
-	PublicKey        string `yaml:"PublicKey,omitempty"`
+	// Algorithm selects a named decryptor (see RegisterAlgorithm) for an operator-specific
+	// protection scheme, instead of the decryptor registered for ProtectionScheme
+	Algorithm  string `yaml:"Algorithm,omitempty"`
+	PrivateKey string `yaml:"PrivateKey,omitempty"`
+	PublicKey  string `yaml:"PublicKey,omitempty"`
+}
+
+const (
+	MinKeyId = 1
+	MaxKeyId = 255
+)
+
+// ProfileTable holds the SUCI profiles by home network public key identifier, so that keys
+// can be added and retired without renumbering the others.
+type ProfileTable map[int]SuciProfile
+
+// NewProfileTable indexes profiles by key ID and rejects out of range or duplicated IDs
+func NewProfileTable(profiles []SuciProfile) (ProfileTable, error) {
+	table := make(ProfileTable, len(profiles))
+	for i, profile := range profiles {
+		keyId := profile.KeyId
+		if keyId == 0 {
+			keyId = i + 1
+		}
+		if keyId < MinKeyId || keyId > MaxKeyId {
+			return nil, fmt.Errorf("SUCI profile key ID %d out of range [%d, %d]", keyId, MinKeyId, MaxKeyId)
+		}
+		if _, ok := table[keyId]; ok {
+			return nil, fmt.Errorf("duplicated SUCI profile key ID %d", keyId)
+		}
+		profile.KeyId = keyId
+		table[keyId] = profile
+	}
+	return table, nil
 }
 
 // profile A.
@@ -173,7 +249,8 @@ func calcSchemeResult(decryptPlainText []byte, supiType string) string {
 			result = result[:len(result)-1]
 		}
 	} else {
-		result = hex.EncodeToString(decryptPlainText)
+		// the username part of a NAI is encrypted as its UTF-8 octets (TS 33.501 C.3.2)
+		result = string(decryptPlainText)
 	}
 	return result
 }
@@ -191,7 +268,7 @@ func decryptWithKdf(sharedKey, kdfPubKey, cipherText, providedMac []byte,
 		return nil, err
 	}
 	if !hmac.Equal(computedMac, providedMac) {
-		return nil, fmt.Errorf("decryption MAC failed")
+		return nil, ErrorMacFailure
 	}
 	logger.SuciLog.Infoln("decryption MAC match")
 
@@ -217,6 +294,16 @@ func ecdhX25519(privateKeyHex string, peerPubKey []byte) ([]byte, error) {
 
 var ErrorPublicKeyUnmarshalling = fmt.Errorf("failed to unmarshal uncompressed public key")
 
+// Errors of the de-concealment that callers tell apart
+var (
+	ErrorUnknownKeyId      = fmt.Errorf("unknown home network public key ID")
+	ErrorMacFailure        = fmt.Errorf("decryption MAC failed")
+	ErrorUnsupportedScheme = fmt.Errorf("unsupported protection scheme")
+)
+
+// ErrorKeyPairMismatch is returned when the PublicKey of a profile is not the one of its PrivateKey
+var ErrorKeyPairMismatch = fmt.Errorf("PublicKey does not match PrivateKey")
+
 func ecdhP256(privateKeyHex string, transmittedPubKey []byte) (sharedKey, kdfPubKey []byte, err error) {
 	bHNPrivBytes, err := hex.DecodeString(privateKeyHex)
 	if err != nil {
@@ -266,18 +353,12 @@ func ecdhP256(privateKeyHex string, transmittedPubKey []byte) (sharedKey, kdfPub
 	return sharedKey, kdfPubKey, nil
 }
 
-func profileA(input, supiType, privateKey string) (string, error) {
+func profileA(s []byte, privateKey string) ([]byte, error) {
 	logger.SuciLog.Infoln("SuciToSupi Profile A")
 
-	s, err := hex.DecodeString(input)
-	if err != nil {
-		logger.SuciLog.Errorln("hex DecodeString error:", err)
-		return "", err
-	}
-
 	const ProfileAPubKeyLen = 32
 	if len(s) < ProfileAPubKeyLen+ProfileAMacLen {
-		return "", fmt.Errorf("suci input too short")
+		return nil, fmt.Errorf("suci input too short")
 	}
 
 	peerPubKey := s[:ProfileAPubKeyLen]
@@ -286,23 +367,18 @@ func profileA(input, supiType, privateKey string) (string, error) {
 
 	sharedKey, err := ecdhX25519(privateKey, peerPubKey)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	plainText, err := decryptWithKdf(sharedKey, peerPubKey, cipherText, providedMac,
+	return decryptWithKdf(sharedKey, peerPubKey, cipherText, providedMac,
 		ProfileAEncKeyLen, ProfileAMacKeyLen, ProfileAHashLen, ProfileAIcbLen, ProfileAMacLen)
-	if err != nil {
-		return "", err
-	}
-	return calcSchemeResult(plainText, supiType), nil
 }
 
-func profileB(input, supiType, privateKey string) (string, error) {
+func profileB(s []byte, privateKey string) ([]byte, error) {
 	logger.SuciLog.Infoln("SuciToSupi Profile B")
 
-	s, err := hex.DecodeString(input)
-	if err != nil || len(s) < 1 {
-		return "", fmt.Errorf("hex DecodeString error: %w", err)
+	if len(s) < 1 {
+		return nil, fmt.Errorf("suci input too short")
 	}
 
 	var ProfileBPubKeyLen int
@@ -312,11 +388,11 @@ func profileB(input, supiType, privateKey string) (string, error) {
 	case 0x04:
 		ProfileBPubKeyLen = 65
 	default:
-		return "", fmt.Errorf("suci input error: unknown public key format")
+		return nil, fmt.Errorf("suci input error: unknown public key format")
 	}
 
 	if len(s) < ProfileBPubKeyLen+ProfileBMacLen {
-		return "", fmt.Errorf("suci input too short")
+		return nil, fmt.Errorf("suci input too short")
 	}
 
 	transmittedPubKey := s[:ProfileBPubKeyLen]
@@ -325,18 +401,24 @@ func profileB(input, supiType, privateKey string) (string, error) {
 
 	sharedKey, kdfPubKey, err := ecdhP256(privateKey, transmittedPubKey)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	plainText, err := decryptWithKdf(sharedKey, kdfPubKey, cipherText, providedMac,
+	return decryptWithKdf(sharedKey, kdfPubKey, cipherText, providedMac,
 		ProfileBEncKeyLen, ProfileBMacKeyLen, ProfileBHashLen, ProfileBIcbLen, ProfileBMacLen)
+}
+
+// ToSupi de-conceals suci with the profiles of suciProfiles, see ProfileTable.ToSupi
+func ToSupi(suci string, suciProfiles []SuciProfile) (string, error) {
+	table, err := NewProfileTable(suciProfiles)
 	if err != nil {
 		return "", err
 	}
-	return calcSchemeResult(plainText, supiType), nil
+	return table.ToSupi(suci)
 }
 
-func ToSupi(suci string, suciProfiles []SuciProfile) (string, error) {
+// ToSupi returns the SUPI concealed in suci, a SUPI is returned as is
+func (t ProfileTable) ToSupi(suci string) (string, error) {
 	parsedSuci := parseSuci(suci)
 	if parsedSuci == nil {
 		if strings.HasPrefix(suci, "imsi-") || strings.HasPrefix(suci, "nai-") {
@@ -348,46 +430,57 @@ func ToSupi(suci string, suciProfiles []SuciProfile) (string, error) {
 
 	logger.SuciLog.Infof("scheme %s", parsedSuci.ProtectionScheme)
 	scheme := parsedSuci.ProtectionScheme
-	mccMnc := parsedSuci.Mcc + parsedSuci.Mnc
-	supiPrefix := PrefixIMSI
+	supiType := parsedSuci.SupiType
 
-	if !strings.HasPrefix(parsedSuci.SupiType, SupiTypeIMSI) {
+	// the SUPI is rebuilt around the decrypted MSIN or username
+	var buildSupi func(result string) (string, error)
+	if supiType == SupiTypeNAI {
 		logger.SuciLog.Infof("SUPI type is NAI")
-		return "", fmt.Errorf("unsupported suciType NAI")
+		buildSupi = func(username string) (string, error) {
+			if username == "" || !utf8.ValidString(username) || strings.ContainsAny(username, "@ ") {
+				return "", fmt.Errorf("invalid NAI username in suci")
+			}
+			return PrefixNAI + username + "@" + parsedSuci.HomeNetworkId, nil
+		}
+	} else {
+		logger.SuciLog.Infof("SUPI type is IMSI")
+		buildSupi = func(msin string) (string, error) {
+			return PrefixIMSI + parsedSuci.Mcc + parsedSuci.Mnc + msin, nil
+		}
 	}
-	logger.SuciLog.Infof("SUPI type is IMSI")
 
 	if scheme == NullScheme {
-		return supiPrefix + mccMnc + parsedSuci.SchemeOutput, nil
+		return buildSupi(parsedSuci.SchemeOutput)
 	}
 
-	keyIndex, err := strconv.Atoi(parsedSuci.PublicKeyID)
+	if !t.supportsScheme(scheme) {
+		return "", fmt.Errorf("%w: protect Scheme (%s) is not supported", ErrorUnsupportedScheme, scheme)
+	}
+	keyId, err := strconv.Atoi(parsedSuci.PublicKeyID)
 	if err != nil {
 		return "", fmt.Errorf("parse HNPublicKeyID error: %w", err)
 	}
-	if keyIndex < 1 || keyIndex > len(suciProfiles) {
-		return "", fmt.Errorf("keyIndex (%d) out of range (%d)", keyIndex, len(suciProfiles))
+	profile, ok := t[keyId]
+	if !ok {
+		return "", fmt.Errorf("%w: no SUCI profile for key ID %d", ErrorUnknownKeyId, keyId)
 	}
-
-	profile := suciProfiles[keyIndex-1]
-	if scheme != profile.ProtectionScheme {
-		return "", fmt.Errorf("protect Scheme mismatch [%s:%s]", scheme, profile.ProtectionScheme)
+	if !strings.EqualFold(scheme, profile.ProtectionScheme) {
+		return "", fmt.Errorf("%w: protect Scheme mismatch [%s:%s]",
+			ErrorUnknownKeyId, scheme, profile.ProtectionScheme)
 	}
 
-	switch scheme {
-	case ProfileAScheme:
-		result, err := profileA(parsedSuci.SchemeOutput, SupiTypeIMSI, profile.PrivateKey)
-		if err != nil {
-			return "", err
-		}
-		return supiPrefix + mccMnc + result, nil
-	case ProfileBScheme:
-		result, err := profileB(parsedSuci.SchemeOutput, SupiTypeIMSI, profile.PrivateKey)
-		if err != nil {
-			return "", err
-		}
-		return supiPrefix + mccMnc + result, nil
-	default:
-		return "", fmt.Errorf("protect Scheme (%s) is not supported", scheme)
+	decryptor, err := profile.decryptor()
+	if err != nil {
+		return "", err
+	}
+	schemeOutput, err := hex.DecodeString(parsedSuci.SchemeOutput)
+	if err != nil {
+		logger.SuciLog.Errorln("hex DecodeString error:", err)
+		return "", err
+	}
+	plainText, err := decryptor.Decrypt(schemeOutput, profile)
+	if err != nil {
+		return "", err
 	}
+	return buildSupi(calcSchemeResult(plainText, supiType))
 }
@@ -1,6 +1,11 @@
 package suci
 
 import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/mlkem"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"testing"
 )
@@ -104,3 +109,411 @@ func TestToSupi(t *testing.T) {
 		}
 	}
 }
+
+func TestToSupiNai(t *testing.T) {
+	suciProfiles := []SuciProfile{
+		{
+			ProtectionScheme: "1", // Protect Scheme: Profile A
+			PrivateKey:       "c53c22208b61860b06c62e5406a7b330c2b577aa5558981510d128247d38bd1d",
+			PublicKey:        "5a8d38864820197c3394b92613b20b91633cbd897119273bf8e4a6f4eec0a650",
+		},
+		{
+			ProtectionScheme: "2", // Protect Scheme: Profile B
+			PrivateKey:       "F1AB1074477EBCC7F554EA1C5FC368B1616730155E0041AC447D6301975FECDA",
+			PublicKey: "0472DA71976234CE833A6907425867B82E074D44EF907DFB4B3E21C1C2256EBCD" +
+				"15A7DED52FCBB097A4ED250E036C7B9C8C7004C4EEDC4F068CD7BF8D3F900E3B4",
+		},
+	}
+	testCases := []struct {
+		name         string
+		suci         string
+		expectedSupi string
+		expectErr    bool
+	}{
+		{
+			name:         "null scheme",
+			suci:         "suci-1-iot.example-operator.com-0-0-0-device-42.sensor",
+			expectedSupi: "nai-device-42.sensor@iot.example-operator.com",
+		},
+		{
+			name: "profile A",
+			suci: "suci-1-iot.example-operator.com-12-1-1-8349edd4313ff2f5005ff04343070e0dacc5dba9082587c321f07c" +
+				"06d2839a2e114b2355da9bcfd437078dd8239532bbb1f785880b726117",
+			expectedSupi: "nai-device-42.sensor@iot.example-operator.com",
+		},
+		{
+			name: "profile B",
+			suci: "suci-1-snpn.example.org-0-2-2-031366d166512b3386551251836934bdb028b69a08d2b6e210625647ebc2f" +
+				"a1290f711be75cd46e1d5ec6cff3571a0502f105f055bedb19784",
+			expectedSupi: "nai-device-42.sensor@snpn.example.org",
+		},
+		{
+			name: "profile A, MAC failure",
+			suci: "suci-1-iot.example-operator.com-12-1-1-8349edd4313ff2f5005ff04343070e0dacc5dba9082587c321f07c" +
+				"06d2839a2e114b2355da9bcfd437078dd8239532bbb1f785880b726118",
+			expectErr: true,
+		},
+		{
+			name:         "null scheme, username with dashes",
+			suci:         "suci-1-example.com-0-0-0-12-34",
+			expectedSupi: "nai-12-34@example.com",
+		},
+		{
+			name:         "null scheme, username and realm with dashes",
+			suci:         "suci-1-iot.example-operator.com-0-0-0-device-1-0-0",
+			expectedSupi: "nai-device-1-0-0@iot.example-operator.com",
+		},
+		{
+			name:      "encrypted scheme output is not hex",
+			suci:      "suci-1-example.com-0-1-1-device-42",
+			expectErr: true,
+		},
+		{
+			name:      "invalid home network identifier",
+			suci:      "suci-1-example..com-0-0-0-device",
+			expectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			supi, err := ToSupi(tc.suci, suciProfiles)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected an error, got supi[%s]", supi)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if supi != tc.expectedSupi {
+				t.Errorf("supi[%s], expected[%s]", supi, tc.expectedSupi)
+			}
+			if !IsNaiSuci(tc.suci) {
+				t.Errorf("IsNaiSuci(%s) is false", tc.suci)
+			}
+		})
+	}
+}
+
+func TestProfileTable(t *testing.T) {
+	profileA := SuciProfile{
+		KeyId:            7,
+		ProtectionScheme: "1",
+		PrivateKey:       "c53c22208b61860b06c62e5406a7b330c2b577aa5558981510d128247d38bd1d",
+		PublicKey:        "5a8d38864820197c3394b92613b20b91633cbd897119273bf8e4a6f4eec0a650",
+	}
+	profileB := SuciProfile{
+		KeyId:            3,
+		ProtectionScheme: "2",
+		PrivateKey:       "F1AB1074477EBCC7F554EA1C5FC368B1616730155E0041AC447D6301975FECDA",
+		PublicKey:        "0272DA71976234CE833A6907425867B82E074D44EF907DFB4B3E21C1C2256EBCD1",
+	}
+	const (
+		profileAOutput = "b2e92f836055a255837debf850b528997ce0201cb82adfe4be1f587d07d8457dcb02352410cddd9e730ef3fa87"
+		profileBOutput = "039aab8376597021e855679a9778ea0b67396e68c66df32c0f41e9acca2da9b9d146a33fc2716ac7dae96aa30a4d"
+	)
+
+	// key 1 was retired, the remaining keys keep their IDs
+	table, err := NewProfileTable([]SuciProfile{profileA, profileB})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	supi, err := table.ToSupi("suci-0-208-93-0-1-7-" + profileAOutput)
+	if err != nil || supi != "imsi-20893001002086" {
+		t.Errorf("key 7: supi[%s] err[%v]", supi, err)
+	}
+	supi, err = table.ToSupi("suci-0-208-93-0-2-3-" + profileBOutput)
+	if err != nil || supi != "imsi-20893001002086" {
+		t.Errorf("key 3: supi[%s] err[%v]", supi, err)
+	}
+	if _, err = table.ToSupi("suci-0-208-93-0-1-1-" + profileAOutput); err == nil {
+		t.Errorf("retired key 1 is still accepted")
+	}
+
+	// profiles without KeyId are numbered by position
+	legacy := []SuciProfile{profileA, profileB}
+	legacy[0].KeyId, legacy[1].KeyId = 0, 0
+	table, err = NewProfileTable(legacy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if table[2].PublicKey != profileB.PublicKey {
+		t.Errorf("profile without KeyId is not numbered by position")
+	}
+
+	duplicated := profileB
+	duplicated.KeyId = 7
+	if _, err = NewProfileTable([]SuciProfile{profileA, duplicated}); err == nil {
+		t.Errorf("duplicated key ID is accepted")
+	}
+	outOfRange := profileB
+	outOfRange.KeyId = 256
+	if _, err = NewProfileTable([]SuciProfile{outOfRange}); err == nil {
+		t.Errorf("key ID 256 is accepted")
+	}
+}
+
+// xorDecryptor stands in for an operator-specific scheme
+type xorDecryptor struct{}
+
+func (xorDecryptor) Decrypt(schemeOutput []byte, profile SuciProfile) ([]byte, error) {
+	key, err := hex.DecodeString(profile.PrivateKey)
+	if err != nil || len(key) == 0 {
+		return nil, errors.New("invalid key")
+	}
+	plainText := make([]byte, len(schemeOutput))
+	for i, b := range schemeOutput {
+		plainText[i] = b ^ key[i%len(key)]
+	}
+	return plainText, nil
+}
+
+func (xorDecryptor) ValidateProfile(profile SuciProfile) error {
+	if profile.PrivateKey == "" {
+		return errors.New("missing key")
+	}
+	return nil
+}
+
+func TestRegisterScheme(t *testing.T) {
+	if err := RegisterScheme("c", xorDecryptor{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() {
+		registryLock.Lock()
+		delete(schemes, "C")
+		registryLock.Unlock()
+	})
+	for _, schemeId := range []string{"0", "1", "C", "G", "10"} {
+		if err := RegisterScheme(schemeId, xorDecryptor{}); err == nil {
+			t.Errorf("scheme [%s] was registered", schemeId)
+		}
+	}
+
+	profiles := []SuciProfile{{KeyId: 5, ProtectionScheme: "C", PrivateKey: "ff"}}
+	if err := ValidateProfile(profiles[0]); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// MSIN 0123456789 is BCD coded with swapped nibbles
+	supi, err := ToSupi("suci-0-208-93-0-c-5-"+hex.EncodeToString([]byte{0xef, 0xcd, 0xab, 0x89, 0x67}), profiles)
+	if err != nil || supi != "imsi-208930123456789" {
+		t.Errorf("supi[%s] err[%v]", supi, err)
+	}
+
+	if _, err = ToSupi("suci-0-208-93-0-d-5-00", []SuciProfile{{KeyId: 5, ProtectionScheme: "D"}}); err == nil {
+		t.Errorf("unregistered scheme D is accepted")
+	}
+}
+
+// sealHybrid conceals plainText like a UE configured with the X25519 ML-KEM-768 hybrid scheme
+func sealHybrid(t *testing.T, publicKey, plainText []byte) []byte {
+	t.Helper()
+	hnX25519, err := ecdh.X25519().NewPublicKey(publicKey[:hybridX25519KeyLen])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := mlkem.NewEncapsulationKey768(publicKey[hybridX25519KeyLen:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdhSharedKey, err := ephemeral.ECDH(hnX25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kemSharedKey, kemCipherText := ek.Encapsulate()
+
+	sharedInfo := append(ephemeral.PublicKey().Bytes(), kemCipherText...)
+	kdfKey := AnsiX963KDF(append(kemSharedKey, ecdhSharedKey...), sharedInfo,
+		ProfileAEncKeyLen, ProfileAMacKeyLen, ProfileAHashLen)
+	cipherText, err := Aes128ctr(plainText, kdfKey[:ProfileAEncKeyLen],
+		kdfKey[ProfileAEncKeyLen:ProfileAEncKeyLen+ProfileAIcbLen])
+	if err != nil {
+		t.Fatal(err)
+	}
+	mac, err := HmacSha256(cipherText, kdfKey[len(kdfKey)-ProfileAMacKeyLen:], ProfileAMacLen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(append(sharedInfo, cipherText...), mac...)
+}
+
+func TestHybridMlKemScheme(t *testing.T) {
+	x25519Key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mlkemKey, err := mlkem.GenerateKey768()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := append(x25519Key.PublicKey().Bytes(), mlkemKey.EncapsulationKey().Bytes()...)
+	profile := SuciProfile{
+		KeyId:            9,
+		ProtectionScheme: "B",
+		Algorithm:        AlgorithmX25519MlKem768,
+		PrivateKey:       hex.EncodeToString(append(x25519Key.Bytes(), mlkemKey.Bytes()...)),
+		PublicKey:        hex.EncodeToString(publicKey),
+	}
+	if err = ValidateProfile(profile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	schemeOutput := sealHybrid(t, publicKey, []byte("device-42.sensor"))
+	supi, err := ToSupi("suci-1-example.com-0-b-9-"+hex.EncodeToString(schemeOutput), []SuciProfile{profile})
+	if err != nil || supi != "nai-device-42.sensor@example.com" {
+		t.Errorf("supi[%s] err[%v]", supi, err)
+	}
+
+	// a tampered ML-KEM ciphertext yields another shared secret, the MAC check fails
+	tampered := bytes.Clone(schemeOutput)
+	tampered[hybridX25519KeyLen] ^= 0x01
+	if _, err = ToSupi("suci-1-example.com-0-b-9-"+hex.EncodeToString(tampered), []SuciProfile{profile}); err == nil {
+		t.Errorf("tampered scheme output is accepted")
+	}
+
+	otherKey, err := mlkem.GenerateKey768()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mismatched := profile
+	mismatched.PublicKey = hex.EncodeToString(append(x25519Key.PublicKey().Bytes(),
+		otherKey.EncapsulationKey().Bytes()...))
+	if err = ValidateProfile(mismatched); err == nil {
+		t.Errorf("mismatched public key is accepted")
+	}
+}
+
+func TestFromSupiRoundTrip(t *testing.T) {
+	profiles := []SuciProfile{
+		{
+			KeyId:            1,
+			ProtectionScheme: "1",
+			PrivateKey:       "c53c22208b61860b06c62e5406a7b330c2b577aa5558981510d128247d38bd1d",
+			PublicKey:        "5a8d38864820197c3394b92613b20b91633cbd897119273bf8e4a6f4eec0a650",
+		},
+		{
+			KeyId:            2,
+			ProtectionScheme: "2",
+			PrivateKey:       "F1AB1074477EBCC7F554EA1C5FC368B1616730155E0041AC447D6301975FECDA",
+			PublicKey: "0472DA71976234CE833A6907425867B82E074D44EF907DFB4B3E21C1C2256EBCD" +
+				"15A7DED52FCBB097A4ED250E036C7B9C8C7004C4EEDC4F068CD7BF8D3F900E3B4",
+		},
+		{
+			KeyId:            3,
+			ProtectionScheme: "2",
+			PrivateKey:       "F1AB1074477EBCC7F554EA1C5FC368B1616730155E0041AC447D6301975FECDA",
+			PublicKey:        "0272DA71976234CE833A6907425867B82E074D44EF907DFB4B3E21C1C2256EBCD1",
+		},
+	}
+	table, err := NewProfileTable(profiles)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, supi := range []string{
+		"imsi-208930000000001", "imsi-00101012345678", "nai-device-42.sensor@example.com",
+		"nai-12-34@example.com",
+	} {
+		for _, profile := range append([]*SuciProfile{nil}, &profiles[0], &profiles[1], &profiles[2]) {
+			concealed, err := FromSupi(supi, 2, "12", profile)
+			if err != nil {
+				t.Fatalf("FromSupi(%s): %v", supi, err)
+			}
+			revealed, err := table.ToSupi(concealed)
+			if err != nil {
+				t.Fatalf("ToSupi(%s): %v", concealed, err)
+			}
+			if revealed != supi {
+				t.Errorf("supi[%s] concealed as [%s] revealed as [%s]", supi, concealed, revealed)
+			}
+		}
+	}
+
+	// every SUCI uses a fresh ephemeral key
+	first, err := FromSupi("imsi-208930000000001", 2, "0", &profiles[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := FromSupi("imsi-208930000000001", 2, "0", &profiles[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first == second {
+		t.Errorf("two SUCIs of the same SUPI are equal")
+	}
+
+	concealed, err := FromSupi("imsi-208930000000001", 3, "0", nil)
+	if err != nil || concealed != "suci-0-208-930-0-0-0-000000001" {
+		t.Errorf("suci[%s] err[%v]", concealed, err)
+	}
+
+	for _, tc := range []struct {
+		supi             string
+		mncLen           int
+		routingIndicator string
+	}{
+		{supi: "imsi-20893", mncLen: 2, routingIndicator: "0"},
+		{supi: "imsi-208930000000001", mncLen: 4, routingIndicator: "0"},
+		{supi: "imsi-208930000000001", mncLen: 2, routingIndicator: "12345"},
+		{supi: "nai-example.com", mncLen: 2, routingIndicator: "0"},
+		{supi: "gci-0011", mncLen: 2, routingIndicator: "0"},
+	} {
+		if _, err := FromSupi(tc.supi, tc.mncLen, tc.routingIndicator, nil); err == nil {
+			t.Errorf("FromSupi(%s, %d, %s) did not fail", tc.supi, tc.mncLen, tc.routingIndicator)
+		}
+	}
+}
+
+func TestGenerateProfile(t *testing.T) {
+	for _, tc := range []struct {
+		protectionScheme string
+		compressed       bool
+		publicKeyLen     int
+	}{
+		{protectionScheme: ProfileAScheme, publicKeyLen: 32},
+		{protectionScheme: ProfileBScheme, publicKeyLen: 65},
+		{protectionScheme: ProfileBScheme, compressed: true, publicKeyLen: 33},
+	} {
+		profile, err := GenerateProfile(9, tc.protectionScheme, tc.compressed)
+		if err != nil {
+			t.Fatalf("GenerateProfile(%s): %v", tc.protectionScheme, err)
+		}
+		if len(profile.PublicKey) != 2*tc.publicKeyLen {
+			t.Errorf("scheme[%s] compressed[%t] public key [%s]", tc.protectionScheme, tc.compressed,
+				profile.PublicKey)
+		}
+		if err := ValidateProfile(profile); err != nil {
+			t.Errorf("scheme[%s] compressed[%t] generated profile invalid: %v",
+				tc.protectionScheme, tc.compressed, err)
+		}
+
+		concealed, err := FromSupi("imsi-208930000000001", 2, "0", &profile)
+		if err != nil {
+			t.Fatalf("FromSupi: %v", err)
+		}
+		if supi, err := ToSupi(concealed, []SuciProfile{profile}); err != nil || supi != "imsi-208930000000001" {
+			t.Errorf("supi[%s] err[%v]", supi, err)
+		}
+
+		other, err := GenerateProfile(9, tc.protectionScheme, tc.compressed)
+		if err != nil {
+			t.Fatalf("GenerateProfile(%s): %v", tc.protectionScheme, err)
+		}
+		profile.PublicKey = other.PublicKey
+		if err := ValidateProfile(profile); !errors.Is(err, ErrorKeyPairMismatch) {
+			t.Errorf("scheme[%s] compressed[%t] mismatched key pair: %v", tc.protectionScheme, tc.compressed, err)
+		}
+	}
+
+	if _, err := GenerateProfile(1, NullScheme, false); !errors.Is(err, ErrorUnsupportedScheme) {
+		t.Errorf("null scheme: %v", err)
+	}
+	if _, err := GenerateProfile(0, ProfileAScheme, false); err == nil {
+		t.Errorf("key ID 0 accepted")
+	}
+}